@@ -47,4 +47,42 @@ LIMIT 5;
 SELECT mem_used_kb / 1024.0 / 1024.0 AS "MEMORIA RAM USADA"
 FROM sys_metrics
 ORDER BY ts DESC
-LIMIT 1;
\ No newline at end of file
+LIMIT 1;
+
+-- PROCESOS POR ESTADO
+SELECT
+  ts * 1000 AS time,
+  running AS "EJECUTANDO (R)",
+  sleeping AS "DURMIENDO (S)",
+  disk_sleep AS "ININTERRUMPIBLE (D)",
+  stopped AS "DETENIDOS (T)",
+  zombie AS "ZOMBIE (Z)",
+  idle AS "INACTIVOS (I)"
+FROM process_count
+ORDER BY ts ASC;
+
+-- PROCESOS ZOMBIE E ININTERRUMPIBLES
+SELECT zombie AS "ZOMBIE (Z)", disk_sleep AS "ININTERRUMPIBLE (D)"
+FROM process_count
+ORDER BY ts DESC
+LIMIT 1;
+
+-- USO DE CPU DEL HOST
+SELECT
+  ts * 1000 AS time,
+  cpu_busy_pct AS "CPU OCUPADA (%)",
+  cpu_user_pct AS "USUARIO (%)",
+  cpu_system_pct AS "SISTEMA (%)",
+  cpu_iowait_pct AS "IOWAIT (%)",
+  cpu_steal_pct AS "STEAL (%)"
+FROM sys_metrics
+WHERE cpu_busy_pct IS NOT NULL
+ORDER BY ts ASC;
+
+-- USO POR NÚCLEO
+SELECT
+  ts * 1000 AS time,
+  'cpu' || cpu AS metric,
+  busy_pct
+FROM cpu_cores
+ORDER BY ts ASC;
\ No newline at end of file
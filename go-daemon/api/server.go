@@ -0,0 +1,187 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"so1-daemon/config"
+	"so1-daemon/database"
+	"so1-daemon/functions"
+	"so1-daemon/metrics"
+	"strconv"
+	"time"
+)
+
+// DEFAULT_LIMIT es la cantidad de filas retornadas por /processes, /deletions y /actions
+// cuando no se indica ?limit=.
+const DEFAULT_LIMIT = 100
+
+// MAX_LIMIT acota ?limit= para no cargar toda la tabla en memoria.
+const MAX_LIMIT = 5000
+
+// NewMux registra los endpoints de la API:
+//
+//	GET /containers  contenedores del último ciclo (?class= filtra por clase)
+//	GET /system      memoria y cantidad de procesos del último ciclo
+//	GET /processes   instantáneas por proceso del host (?pid=, ?limit=, ?from=, ?to=)
+//	GET /deletions   últimas eliminaciones (?limit=, ?from=, ?to=)
+//	GET /actions     últimas acciones de la escalera, incluidas las de dry-run (?limit=, ?from=, ?to=)
+//	GET /config      configuración activa
+//	GET /metrics     métricas en formato de texto de Prometheus
+func NewMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /containers", handleContainers)
+	mux.HandleFunc("GET /system", handleSystem)
+	mux.HandleFunc("GET /processes", handleProcesses)
+	mux.HandleFunc("GET /deletions", handleDeletions)
+	mux.HandleFunc("GET /actions", handleActions)
+	mux.HandleFunc("GET /config", handleConfig)
+	mux.HandleFunc("GET /metrics", handleMetrics)
+	return mux
+}
+
+// Start levanta el servidor HTTP en addr en segundo plano. Los errores al
+// escuchar se registran en el log sin detener el daemon.
+func Start(addr string, handler http.Handler) *http.Server {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      15 * time.Second,
+	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("Advertencia: servidor HTTP en %s: %v", addr, err)
+		}
+	}()
+	return srv
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// parseRange lee ?limit=, ?from= y ?to= (Unix, segundos) aplicando el
+// límite por defecto y el máximo.
+func parseRange(r *http.Request) (database.Range, string) {
+	rg := database.Range{Limit: DEFAULT_LIMIT}
+	q := r.URL.Query()
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return rg, "limit debe ser un entero positivo"
+		}
+		rg.Limit = min(n, MAX_LIMIT)
+	}
+	for _, p := range []struct {
+		name string
+		dst  *int64
+	}{{"from", &rg.From}, {"to", &rg.To}} {
+		if v := q.Get(p.name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				return rg, p.name + " debe ser una marca de tiempo Unix"
+			}
+			*p.dst = n
+		}
+	}
+	return rg, ""
+}
+
+func handleContainers(w http.ResponseWriter, r *http.Request) {
+	snap := functions.LastSnapshot()
+	containers := snap.Containers
+
+	if class := r.URL.Query().Get("class"); class != "" {
+		filtered := containers[:0]
+		for _, c := range containers {
+			if c.Class == class {
+				filtered = append(filtered, c)
+			}
+		}
+		containers = filtered
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ts":         snap.Timestamp,
+		"containers": containers,
+	})
+}
+
+func handleSystem(w http.ResponseWriter, r *http.Request) {
+	snap := functions.LastSnapshot()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ts":     snap.Timestamp,
+		"system": snap.System,
+	})
+}
+
+func handleProcesses(w http.ResponseWriter, r *http.Request) {
+	rg, msg := parseRange(r)
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	pid := 0
+	if v := r.URL.Query().Get("pid"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "pid debe ser un entero positivo")
+			return
+		}
+		pid = n
+	}
+	rows, err := database.Store.QueryProcesses(pid, rg)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, rows)
+}
+
+func handleDeletions(w http.ResponseWriter, r *http.Request) {
+	rg, msg := parseRange(r)
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	rows, err := database.Store.QueryDeletions(rg)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, rows)
+}
+
+func handleActions(w http.ResponseWriter, r *http.Request) {
+	rg, msg := parseRange(r)
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	rows, err := database.Store.QueryActions(rg)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, rows)
+}
+
+func handleConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, config.Get().Redacted())
+}
+
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metrics.CONTENT_TYPE)
+	if err := metrics.WriteText(w, functions.LastSnapshot()); err != nil {
+		log.Printf("Advertencia: no se pudieron escribir las métricas: %v", err)
+	}
+}
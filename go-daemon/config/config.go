@@ -0,0 +1,704 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"so1-daemon/rules"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ENV_PREFIX es el prefijo de las variables de entorno que sobrescriben
+// valores de la configuración (por ejemplo SO1_CPU_THRESHOLD).
+const ENV_PREFIX = "SO1_"
+
+// Duration permite escribir intervalos en el archivo JSON como "20s" o "1m".
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("la duración debe ser un texto como \"20s\": %v", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// Modos de detección de violaciones
+const (
+	DETECT_INSTANT = "instant" // una sola muestra sobre el umbral
+	DETECT_WINDOW  = "window"  // Required de las últimas Window muestras
+	DETECT_EWMA    = "ewma"    // promedio móvil exponencial sobre el umbral
+)
+
+// Detection define cuándo una violación se considera sostenida.
+type Detection struct {
+	Mode     string  `json:"mode"`
+	Window   int     `json:"window"`   // muestras consideradas (modo window)
+	Required int     `json:"required"` // muestras sobre el umbral necesarias
+	Alpha    float64 `json:"alpha"`    // peso de la muestra nueva (modo ewma)
+	// Tiempo sin reevaluar un contenedor después de aplicarle una acción
+	Cooldown Duration `json:"cooldown"`
+}
+
+// Retention define cuánto tiempo se conservan los datos en monitor.db.
+// Una duración 0 conserva los datos indefinidamente.
+type Retention struct {
+	Raw    Duration `json:"raw"`    // containers, sys_metrics, cpu_cores, process_count y processes
+	Minute Duration `json:"minute"` // agregados de 1 minuto (containers_1m)
+	Hour   Duration `json:"hour"`   // agregados de 1 hora (containers_1h)
+	// Frecuencia del mantenimiento (rollups y borrado)
+	Interval Duration `json:"interval"`
+	// Frecuencia de VACUUM; 0 lo deshabilita
+	Vacuum Duration `json:"vacuum"`
+}
+
+// Modos de lectura de PROC_SYS y PROC_CONT
+const (
+	PROC_PARSE_STRICT   = "strict"   // un registro inválido hace fallar el ciclo
+	PROC_PARSE_TOLERANT = "tolerant" // se descartan solo los registros inválidos
+)
+
+// Criterios para elegir los procesos guardados en cada ciclo
+const (
+	PROC_SORT_MEM = "mem" // mayor mem_pct
+	PROC_SORT_RSS = "rss" // mayor rss_kb
+	PROC_SORT_CPU = "cpu" // mayor cpu_pct
+)
+
+// ProcessSnapshots controla el registro por proceso de PROC_SYS en la
+// tabla processes. Solo se guardan los TopN procesos con mayor consumo
+// según SortBy para acotar el volumen.
+type ProcessSnapshots struct {
+	Enabled bool   `json:"enabled"`
+	TopN    int    `json:"top_n"` // 0 guarda todos los procesos
+	SortBy  string `json:"sort_by"`
+}
+
+// StateAlerts define a partir de cuántos procesos zombie (Z) o en espera
+// ininterrumpible (D) se registra una advertencia. 0 deshabilita la alerta.
+type StateAlerts struct {
+	Zombie    int `json:"zombie"`
+	DiskSleep int `json:"disk_sleep"`
+}
+
+// Config agrupa todos los parámetros ajustables del daemon.
+type Config struct {
+	// Archivos /proc generados por los módulos del kernel
+	ProcCont string `json:"proc_cont"`
+	ProcSys  string `json:"proc_sys"`
+	// Validación de su contenido (PROC_PARSE_*)
+	ProcParse string `json:"proc_parse"`
+
+	// Almacenamiento de métricas: "sqlite", "postgres" o "memory"
+	Storage string `json:"storage"`
+
+	// Ruta de la base de datos SQLite
+	DBPath string `json:"db_path"`
+
+	// Conexión a PostgreSQL (ej. "postgres://so1:clave@db:5432/so1?sslmode=disable")
+	PostgresDSN string `json:"postgres_dsn"`
+
+	// Nombre con el que se registran las filas de este host; permite
+	// centralizar varios hosts en una misma base PostgreSQL
+	HostName string `json:"host_name"`
+
+	// Runtime de contenedores: "docker", "containerd" o "podman"
+	Runtime string `json:"runtime"`
+
+	// Socket unix del Docker Engine API
+	DockerSocket string `json:"docker_socket"`
+
+	// Socket de la API compatible con Docker de Podman
+	PodmanSocket string `json:"podman_socket"`
+
+	// Socket de containerd y namespaces a recorrer (k8s.io para CRI)
+	ContainerdAddress    string   `json:"containerd_address"`
+	ContainerdNamespaces []string `json:"containerd_namespaces"`
+
+	// Intervalo entre cada ejecución de ProcessOnce
+	Interval Duration `json:"interval"`
+
+	// Dirección de la API HTTP (ej. "127.0.0.1:8090"); vacío la deshabilita
+	HTTPListen string `json:"http_listen"`
+
+	// Umbrales (%)
+	CPUThreshold float64 `json:"cpu_threshold"`
+	MemThreshold float64 `json:"mem_threshold"`
+	// Escala de cpu_threshold para las clases que no indican la suya
+	// (rules.CPU_VIEW_*)
+	CPUView string `json:"cpu_view"`
+
+	// Mínimos
+	MinLowContainers  int `json:"min_low_containers"`
+	MinHighContainers int `json:"min_high_containers"`
+
+	// Detección de violaciones sostenidas (histéresis)
+	Detection Detection `json:"detection"`
+
+	// Retención y agregados de la base de datos
+	Retention Retention `json:"retention"`
+
+	// Instantáneas por proceso del host
+	ProcessSnapshots ProcessSnapshots `json:"process_snapshots"`
+
+	// Límites de procesos por estado
+	StateAlerts StateAlerts `json:"state_alerts"`
+
+	// Modo observación: se calculan y registran las decisiones pero
+	// nunca se elimina ningún contenedor
+	DryRun bool `json:"dry_run"`
+
+	// Clases de contenedores; si se omite se usan rules.DefaultClasses con
+	// los mínimos anteriores.
+	Classes []rules.Class `json:"classes,omitempty"`
+
+	// Motor de reglas compilado a partir de Classes
+	Rules *rules.Engine `json:"-"`
+}
+
+// Default retorna la configuración con los valores históricos del daemon.
+func Default() *Config {
+	cfg := &Config{
+		ProcCont:          "/proc/continfo_so1_202041390",
+		ProcSys:           "/proc/sysinfo_so1_202041390",
+		ProcParse:         PROC_PARSE_TOLERANT,
+		Storage:           "sqlite",
+		DBPath:            "./data/monitor.db",
+		HostName:          hostname(),
+		Runtime:           "docker",
+		DockerSocket:      "/var/run/docker.sock",
+		PodmanSocket:      "/run/podman/podman.sock",
+		ContainerdAddress: "/run/containerd/containerd.sock",
+		ContainerdNamespaces: []string{
+			"k8s.io",
+			"default",
+		},
+		Interval:          Duration{20 * time.Second},
+		HTTPListen:        "127.0.0.1:8090",
+		CPUThreshold:      20.0,
+		MemThreshold:      20.0,
+		CPUView:           rules.CPU_VIEW_RAW,
+		MinLowContainers:  3,
+		MinHighContainers: 2,
+		Detection: Detection{
+			Mode:     DETECT_WINDOW,
+			Window:   5,
+			Required: 3,
+			Alpha:    0.5,
+			Cooldown: Duration{60 * time.Second},
+		},
+		Retention: Retention{
+			Raw:      Duration{24 * time.Hour},
+			Minute:   Duration{7 * 24 * time.Hour},
+			Hour:     Duration{90 * 24 * time.Hour},
+			Interval: Duration{5 * time.Minute},
+			Vacuum:   Duration{24 * time.Hour},
+		},
+		ProcessSnapshots: ProcessSnapshots{
+			Enabled: false,
+			TopN:    20,
+			SortBy:  PROC_SORT_MEM,
+		},
+		StateAlerts: StateAlerts{
+			Zombie:    20,
+			DiskSleep: 10,
+		},
+	}
+	cfg.Rules, _ = rules.Compile(rules.DefaultClasses(cfg.MinLowContainers, cfg.MinHighContainers))
+	return cfg
+}
+
+// field describe un parámetro que puede sobrescribirse por entorno o por flag.
+type field struct {
+	name  string // nombre del flag; la variable de entorno se deriva de él
+	usage string
+	set   func(c *Config, v string) error
+}
+
+var fields = []field{
+	{"dry-run", "modo observación: registra las decisiones sin eliminar contenedores (true/false)", func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		c.DryRun = b
+		return err
+	}},
+	{"detection-mode", "detección de violaciones: instant, window o ewma", func(c *Config, v string) error {
+		c.Detection.Mode = v
+		return nil
+	}},
+	{"detection-window", "muestras de la ventana de detección", func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		c.Detection.Window = n
+		return err
+	}},
+	{"detection-required", "muestras sobre el umbral necesarias dentro de la ventana", func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		c.Detection.Required = n
+		return err
+	}},
+	{"detection-alpha", "peso de la muestra nueva en el modo ewma (0-1]", func(c *Config, v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		c.Detection.Alpha = f
+		return err
+	}},
+	{"detection-cooldown", "tiempo sin reevaluar un contenedor tras una acción (ej. 60s)", func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		c.Detection.Cooldown = Duration{d}
+		return err
+	}},
+	{"retention-raw", "retención de las métricas sin agregar (ej. 24h, 0 = sin límite)", func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		c.Retention.Raw = Duration{d}
+		return err
+	}},
+	{"retention-minute", "retención de los agregados de 1 minuto (0 = sin límite)", func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		c.Retention.Minute = Duration{d}
+		return err
+	}},
+	{"retention-hour", "retención de los agregados de 1 hora (0 = sin límite)", func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		c.Retention.Hour = Duration{d}
+		return err
+	}},
+	{"retention-interval", "frecuencia del mantenimiento de la base (ej. 5m)", func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		c.Retention.Interval = Duration{d}
+		return err
+	}},
+	{"retention-vacuum", "frecuencia de VACUUM (0 = nunca)", func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		c.Retention.Vacuum = Duration{d}
+		return err
+	}},
+	{"process-snapshots", "guardar las instantáneas por proceso del host (true/false)", func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		c.ProcessSnapshots.Enabled = b
+		return err
+	}},
+	{"process-top-n", "procesos guardados por ciclo (0 = todos)", func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		c.ProcessSnapshots.TopN = n
+		return err
+	}},
+	{"process-sort-by", "criterio del top de procesos: mem, rss o cpu", func(c *Config, v string) error {
+		c.ProcessSnapshots.SortBy = v
+		return nil
+	}},
+	{"state-alert-zombie", "procesos zombie a partir de los cuales se advierte (0 = nunca)", func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		c.StateAlerts.Zombie = n
+		return err
+	}},
+	{"state-alert-disk-sleep", "procesos en estado D a partir de los cuales se advierte (0 = nunca)", func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		c.StateAlerts.DiskSleep = n
+		return err
+	}},
+	{"proc-cont", "archivo /proc de contenedores", func(c *Config, v string) error {
+		c.ProcCont = v
+		return nil
+	}},
+	{"proc-sys", "archivo /proc del sistema", func(c *Config, v string) error {
+		c.ProcSys = v
+		return nil
+	}},
+	{"proc-parse", "lectura de los archivos /proc: strict (un registro inválido hace fallar el ciclo) o tolerant (se descarta el registro)", func(c *Config, v string) error {
+		c.ProcParse = v
+		return nil
+	}},
+	{"storage", "almacenamiento de métricas: sqlite, postgres o memory", func(c *Config, v string) error {
+		c.Storage = v
+		return nil
+	}},
+	{"db-path", "ruta de la base de datos SQLite", func(c *Config, v string) error {
+		c.DBPath = v
+		return nil
+	}},
+	{"postgres-dsn", "conexión a PostgreSQL (storage=postgres)", func(c *Config, v string) error {
+		c.PostgresDSN = v
+		return nil
+	}},
+	{"host-name", "nombre del host registrado en cada fila", func(c *Config, v string) error {
+		c.HostName = v
+		return nil
+	}},
+	{"runtime", "runtime de contenedores: docker, containerd o podman", func(c *Config, v string) error {
+		c.Runtime = v
+		return nil
+	}},
+	{"docker-socket", "socket unix del Docker Engine API", func(c *Config, v string) error {
+		c.DockerSocket = v
+		return nil
+	}},
+	{"podman-socket", "socket unix de la API de Podman", func(c *Config, v string) error {
+		c.PodmanSocket = v
+		return nil
+	}},
+	{"containerd-address", "socket de containerd", func(c *Config, v string) error {
+		c.ContainerdAddress = v
+		return nil
+	}},
+	{"containerd-namespaces", "namespaces de containerd separados por coma", func(c *Config, v string) error {
+		c.ContainerdNamespaces = splitList(v)
+		return nil
+	}},
+	{"interval", "intervalo entre mediciones (ej. 20s)", func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		c.Interval = Duration{d}
+		return err
+	}},
+	{"http-listen", "dirección de la API HTTP (vacío la deshabilita)", func(c *Config, v string) error {
+		c.HTTPListen = v
+		return nil
+	}},
+	{"cpu-threshold", "umbral de CPU en %", func(c *Config, v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		c.CPUThreshold = f
+		return err
+	}},
+	{"cpu-view", "escala del umbral de CPU: raw (100% por núcleo), host (todos los núcleos) o quota (cuota del contenedor)", func(c *Config, v string) error {
+		c.CPUView = v
+		return nil
+	}},
+	{"mem-threshold", "umbral de memoria en %", func(c *Config, v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		c.MemThreshold = f
+		return err
+	}},
+	{"min-low-containers", "mínimo de contenedores de bajo consumo", func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		c.MinLowContainers = n
+		return err
+	}},
+	{"min-high-containers", "mínimo de contenedores de alto consumo", func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		c.MinHighContainers = n
+		return err
+	}},
+}
+
+// splitList separa una lista "a, b,c" descartando elementos vacíos.
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// envName convierte "cpu-threshold" en "SO1_CPU_THRESHOLD".
+func envName(name string) string {
+	return ENV_PREFIX + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Overrides guarda los flags de línea de comandos registrados por RegisterFlags.
+type Overrides struct {
+	fs *flag.FlagSet
+}
+
+// RegisterFlags declara un flag por cada parámetro ajustable en fs.
+func RegisterFlags(fs *flag.FlagSet) *Overrides {
+	for _, f := range fields {
+		fs.String(f.name, "", fmt.Sprintf("%s (env %s)", f.usage, envName(f.name)))
+	}
+	return &Overrides{fs: fs}
+}
+
+// Values retorna únicamente los flags que fueron indicados explícitamente.
+func (o *Overrides) Values() map[string]string {
+	values := make(map[string]string)
+	if o == nil || o.fs == nil {
+		return values
+	}
+	o.fs.Visit(func(f *flag.Flag) {
+		values[f.Name] = f.Value.String()
+	})
+	return values
+}
+
+// Load construye la configuración aplicando, en orden de prioridad creciente:
+// valores por defecto, archivo JSON (si path no está vacío), variables de
+// entorno SO1_* y los overrides de línea de comandos.
+func Load(path string, overrides map[string]string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("leer configuración: %v", err)
+		}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("analizar configuración %s: %v", path, err)
+		}
+	}
+
+	for _, f := range fields {
+		if v, ok := os.LookupEnv(envName(f.name)); ok {
+			if err := f.set(cfg, v); err != nil {
+				return nil, fmt.Errorf("variable %s=%q inválida: %v", envName(f.name), v, err)
+			}
+		}
+	}
+
+	for _, f := range fields {
+		if v, ok := overrides[f.name]; ok {
+			if err := f.set(cfg, v); err != nil {
+				return nil, fmt.Errorf("flag -%s=%q inválido: %v", f.name, v, err)
+			}
+		}
+	}
+
+	if cfg.Classes == nil {
+		cfg.Classes = rules.DefaultClasses(cfg.MinLowContainers, cfg.MinHighContainers)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	engine, err := rules.Compile(cfg.Classes)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Rules = engine
+	return cfg, nil
+}
+
+// Validate revisa que todos los valores estén en rangos aceptables y
+// retorna un error con la lista completa de problemas encontrados.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.ProcCont == "" {
+		errs = append(errs, errors.New("proc_cont no puede estar vacío"))
+	}
+	if c.ProcSys == "" {
+		errs = append(errs, errors.New("proc_sys no puede estar vacío"))
+	}
+	switch c.ProcParse {
+	case PROC_PARSE_STRICT, PROC_PARSE_TOLERANT:
+	default:
+		errs = append(errs, fmt.Errorf("proc_parse debe ser strict o tolerant (actual %q)", c.ProcParse))
+	}
+	switch c.Storage {
+	case "sqlite":
+		if c.DBPath == "" {
+			errs = append(errs, errors.New("db_path no puede estar vacío"))
+		}
+	case "postgres":
+		if c.PostgresDSN == "" {
+			errs = append(errs, errors.New("postgres_dsn no puede estar vacío con storage=postgres"))
+		}
+	case "memory":
+	default:
+		errs = append(errs, fmt.Errorf("storage debe ser sqlite, postgres o memory (actual %q)", c.Storage))
+	}
+	if c.HostName == "" {
+		errs = append(errs, errors.New("host_name no puede estar vacío"))
+	}
+	switch c.Runtime {
+	case "docker":
+		if c.DockerSocket == "" {
+			errs = append(errs, errors.New("docker_socket no puede estar vacío"))
+		}
+	case "podman":
+		if c.PodmanSocket == "" {
+			errs = append(errs, errors.New("podman_socket no puede estar vacío"))
+		}
+	case "containerd":
+		if c.ContainerdAddress == "" {
+			errs = append(errs, errors.New("containerd_address no puede estar vacío"))
+		}
+		if len(c.ContainerdNamespaces) == 0 {
+			errs = append(errs, errors.New("containerd_namespaces debe tener al menos un namespace"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("runtime debe ser docker, containerd o podman (actual %q)", c.Runtime))
+	}
+	if c.Interval.Duration < time.Second {
+		errs = append(errs, fmt.Errorf("interval debe ser al menos 1s (actual %s)", c.Interval.Duration))
+	}
+	if c.HTTPListen != "" {
+		if _, _, err := net.SplitHostPort(c.HTTPListen); err != nil {
+			errs = append(errs, fmt.Errorf("http_listen inválido (actual %q): %v", c.HTTPListen, err))
+		}
+	}
+	if c.CPUThreshold <= 0 {
+		errs = append(errs, fmt.Errorf("cpu_threshold debe ser mayor que 0 (actual %.2f)", c.CPUThreshold))
+	}
+	switch c.CPUView {
+	case rules.CPU_VIEW_RAW, rules.CPU_VIEW_HOST, rules.CPU_VIEW_QUOTA:
+	default:
+		errs = append(errs, fmt.Errorf("cpu_view debe ser raw, host o quota (actual %q)", c.CPUView))
+	}
+	if c.MemThreshold <= 0 || c.MemThreshold > 100 {
+		errs = append(errs, fmt.Errorf("mem_threshold debe estar entre 0 y 100 (actual %.2f)", c.MemThreshold))
+	}
+	if c.MinLowContainers < 0 {
+		errs = append(errs, fmt.Errorf("min_low_containers no puede ser negativo (actual %d)", c.MinLowContainers))
+	}
+	if c.MinHighContainers < 0 {
+		errs = append(errs, fmt.Errorf("min_high_containers no puede ser negativo (actual %d)", c.MinHighContainers))
+	}
+
+	d := c.Detection
+	switch d.Mode {
+	case DETECT_INSTANT:
+	case DETECT_WINDOW:
+		if d.Window < 1 || d.Required < 1 || d.Required > d.Window {
+			errs = append(errs, fmt.Errorf("detection: se requiere 1 <= required <= window (actual %d de %d)", d.Required, d.Window))
+		}
+	case DETECT_EWMA:
+		if d.Alpha <= 0 || d.Alpha > 1 {
+			errs = append(errs, fmt.Errorf("detection.alpha debe estar en (0, 1] (actual %.2f)", d.Alpha))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("detection.mode debe ser instant, window o ewma (actual %q)", d.Mode))
+	}
+	if d.Cooldown.Duration < 0 {
+		errs = append(errs, fmt.Errorf("detection.cooldown no puede ser negativo (actual %s)", d.Cooldown.Duration))
+	}
+
+	r := c.Retention
+	if r.Raw.Duration < 0 || r.Minute.Duration < 0 || r.Hour.Duration < 0 || r.Vacuum.Duration < 0 {
+		errs = append(errs, errors.New("retention: las duraciones no pueden ser negativas"))
+	}
+	// Los datos sin agregar deben vivir lo suficiente para cerrar el bucket de 1 hora
+	if r.Raw.Duration > 0 && r.Raw.Duration < 2*time.Hour {
+		errs = append(errs, fmt.Errorf("retention.raw debe ser al menos 2h o 0 (actual %s)", r.Raw.Duration))
+	}
+	if r.Interval.Duration < time.Minute {
+		errs = append(errs, fmt.Errorf("retention.interval debe ser al menos 1m (actual %s)", r.Interval.Duration))
+	}
+
+	ps := c.ProcessSnapshots
+	if ps.TopN < 0 {
+		errs = append(errs, fmt.Errorf("process_snapshots.top_n no puede ser negativo (actual %d)", ps.TopN))
+	}
+	switch ps.SortBy {
+	case PROC_SORT_MEM, PROC_SORT_RSS, PROC_SORT_CPU:
+	default:
+		errs = append(errs, fmt.Errorf("process_snapshots.sort_by debe ser mem, rss o cpu (actual %q)", ps.SortBy))
+	}
+
+	if c.StateAlerts.Zombie < 0 || c.StateAlerts.DiskSleep < 0 {
+		errs = append(errs, errors.New("state_alerts: los límites no pueden ser negativos"))
+	}
+
+	if _, err := rules.Compile(c.Classes); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("configuración inválida:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// hostname retorna el nombre del host o "localhost" si no se puede obtener.
+func hostname() string {
+	if h, err := os.Hostname(); err == nil && h != "" {
+		return h
+	}
+	return "localhost"
+}
+
+var dsnPassword = regexp.MustCompile(`(?i)(password\s*=\s*)('[^']*'|\S+)`)
+
+// RedactDSN oculta la clave de una cadena de conexión de PostgreSQL, tanto
+// en formato URL como clave=valor.
+func RedactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" && u.User != nil {
+		return u.Redacted()
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}xxxxx")
+}
+
+// Redacted retorna una copia de la configuración apta para mostrarse (por
+// ejemplo en la API HTTP), sin credenciales.
+func (c *Config) Redacted() *Config {
+	r := *c
+	r.PostgresDSN = RedactDSN(c.PostgresDSN)
+	return &r
+}
+
+// current es la configuración activa del daemon. Se guarda en un puntero
+// atómico para que una recarga (SIGHUP) la reemplace sin bloquear el bucle.
+var current atomic.Pointer[Config]
+
+func init() {
+	current.Store(Default())
+}
+
+// Get retorna la configuración activa. El valor retornado no debe
+// modificarse; cada ciclo debe tomar su propia copia con Get().
+func Get() *Config {
+	return current.Load()
+}
+
+// Set reemplaza la configuración activa de forma atómica.
+func Set(c *Config) {
+	current.Store(c)
+}
+
+// Reload vuelve a construir la configuración desde las mismas fuentes que
+// Load y la activa. Si la nueva configuración es inválida se conserva la
+// anterior. Los campos que solo se leen al arrancar (almacenamiento,
+// runtime de contenedores y API HTTP) se mantienen y se retorna la lista de
+// advertencias correspondiente.
+func Reload(path string, overrides map[string]string) (*Config, []string, error) {
+	next, err := Load(path, overrides)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prev := Get()
+	var warnings []string
+	keep := func(name string, nextVal, prevVal any, restore func()) {
+		if fmt.Sprint(nextVal) != fmt.Sprint(prevVal) {
+			warnings = append(warnings, fmt.Sprintf("%s cambió a %v; se requiere reiniciar, se mantiene %v", name, nextVal, prevVal))
+			restore()
+		}
+	}
+	keep("storage", next.Storage, prev.Storage, func() { next.Storage = prev.Storage })
+	keep("db_path", next.DBPath, prev.DBPath, func() { next.DBPath = prev.DBPath })
+	keep("host_name", next.HostName, prev.HostName, func() { next.HostName = prev.HostName })
+	// La cadena de conexión puede contener la clave: no se muestra
+	if next.PostgresDSN != prev.PostgresDSN {
+		warnings = append(warnings, "postgres_dsn cambió; se requiere reiniciar, se mantiene el anterior")
+		next.PostgresDSN = prev.PostgresDSN
+	}
+	keep("runtime", next.Runtime, prev.Runtime, func() { next.Runtime = prev.Runtime })
+	keep("docker_socket", next.DockerSocket, prev.DockerSocket, func() { next.DockerSocket = prev.DockerSocket })
+	keep("podman_socket", next.PodmanSocket, prev.PodmanSocket, func() { next.PodmanSocket = prev.PodmanSocket })
+	keep("containerd_address", next.ContainerdAddress, prev.ContainerdAddress, func() { next.ContainerdAddress = prev.ContainerdAddress })
+	keep("containerd_namespaces", next.ContainerdNamespaces, prev.ContainerdNamespaces, func() { next.ContainerdNamespaces = prev.ContainerdNamespaces })
+	keep("http_listen", next.HTTPListen, prev.HTTPListen, func() { next.HTTPListen = prev.HTTPListen })
+
+	Set(next)
+	return next, warnings, nil
+}
@@ -0,0 +1,337 @@
+package cruntime
+
+import (
+	"encoding/json"
+	"fmt"
+	"so1-daemon/utils"
+	"so1-daemon/var_const"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// containerdRuntime implementa ContainerRuntime para hosts que ejecutan
+// containerd sin dockerd (por ejemplo nodos Kubernetes con CRI). Usa el
+// cliente `ctr` y recorre cada namespace configurado (k8s.io, default, ...).
+type containerdRuntime struct {
+	address    string
+	namespaces []string
+
+	// cache guarda la información por Container ID junto con su ruta de cgroup
+	cache     map[string]containerdEntry
+	cacheLock sync.Mutex
+}
+
+type containerdEntry struct {
+	info        var_const.DockerInfo
+	cgroupsPath string
+}
+
+// containerdInfo contiene los campos de `ctr containers info` que usa el daemon.
+type containerdInfo struct {
+	ID     string            `json:"ID"`
+	Image  string            `json:"Image"`
+	Labels map[string]string `json:"Labels"`
+	Spec   struct {
+		Linux struct {
+			CgroupsPath string `json:"cgroupsPath"`
+		} `json:"linux"`
+	} `json:"Spec"`
+}
+
+// NewContainerd crea el runtime de containerd para el socket y namespaces indicados.
+func NewContainerd(address string, namespaces []string) ContainerRuntime {
+	return &containerdRuntime{
+		address:    address,
+		namespaces: namespaces,
+		cache:      make(map[string]containerdEntry),
+	}
+}
+
+func (r *containerdRuntime) Name() string {
+	return "containerd"
+}
+
+// ctr ejecuta el cliente de containerd en el namespace indicado.
+func (r *containerdRuntime) ctr(namespace string, args ...string) (string, error) {
+	base := []string{"--address", r.address, "--namespace", namespace}
+	return utils.RunCommand("ctr", append(base, args...)...)
+}
+
+// containerName obtiene un nombre legible a partir de las etiquetas de CRI
+// o nerdctl; si no existen se usa el ID.
+func containerName(id string, labels map[string]string) string {
+	for _, k := range []string{"io.kubernetes.container.name", "nerdctl/name"} {
+		if v := labels[k]; v != "" {
+			return v
+		}
+	}
+	return id
+}
+
+// inspect consulta `ctr containers info` y guarda el resultado en caché.
+// Debe llamarse con cacheLock tomado.
+func (r *containerdRuntime) inspect(namespace, id string, pid int) (containerdEntry, error) {
+	out, err := r.ctr(namespace, "containers", "info", id)
+	if err != nil {
+		return containerdEntry{}, err
+	}
+	var ci containerdInfo
+	if err := json.Unmarshal([]byte(out), &ci); err != nil {
+		return containerdEntry{}, fmt.Errorf("salida inválida de ctr containers info %s: %v", id, err)
+	}
+	entry := containerdEntry{
+		info: var_const.DockerInfo{
+			ContainerID: ci.ID,
+			Image:       ci.Image,
+			Pid:         pid,
+			Name:        containerName(ci.ID, ci.Labels),
+			Namespace:   namespace,
+			Labels:      ci.Labels,
+		},
+		cgroupsPath: ci.Spec.Linux.CgroupsPath,
+	}
+	r.cache[ci.ID] = entry
+	return entry, nil
+}
+
+// PidMap recorre `ctr task ls` en cada namespace. Formato de salida:
+//
+//	TASK    PID     STATUS
+//	<id>    1234    RUNNING
+func (r *containerdRuntime) PidMap() (map[int]var_const.DockerInfo, error) {
+	r.cacheLock.Lock()
+	defer r.cacheLock.Unlock()
+
+	result := make(map[int]var_const.DockerInfo)
+	alive := make(map[string]bool)
+	var lastErr error
+	okNamespaces := 0
+
+	for _, ns := range r.namespaces {
+		out, err := r.ctr(ns, "tasks", "ls")
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		okNamespaces++
+
+		for _, line := range strings.Split(out, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 3 || fields[0] == "TASK" {
+				continue
+			}
+			if fields[2] != "RUNNING" && fields[2] != "PAUSED" {
+				continue
+			}
+			pid, err := strconv.Atoi(fields[1])
+			if err != nil || pid == 0 {
+				continue
+			}
+			id := fields[0]
+			alive[id] = true
+
+			entry, ok := r.cache[id]
+			if !ok || entry.info.Pid != pid {
+				entry, err = r.inspect(ns, id, pid)
+				if err != nil {
+					continue
+				}
+			}
+			result[pid] = entry.info
+		}
+	}
+
+	if okNamespaces == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	for id := range r.cache {
+		if !alive[id] {
+			delete(r.cache, id)
+		}
+	}
+	return result, nil
+}
+
+func (r *containerdRuntime) InfoByID(id string) (var_const.DockerInfo, error) {
+	r.cacheLock.Lock()
+	defer r.cacheLock.Unlock()
+
+	for cid, entry := range r.cache {
+		if strings.HasPrefix(cid, id) {
+			return entry.info, nil
+		}
+	}
+
+	// Buscar la tarea en cada namespace para obtener su PID
+	for _, ns := range r.namespaces {
+		out, err := r.ctr(ns, "tasks", "ps", id)
+		if err != nil {
+			continue
+		}
+		// PID    INFO
+		// 1234   -
+		for _, line := range strings.Split(out, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			pid, err := strconv.Atoi(fields[0])
+			if err != nil || pid == 0 {
+				continue
+			}
+			entry, err := r.inspect(ns, id, pid)
+			if err != nil {
+				return var_const.DockerInfo{}, err
+			}
+			return entry.info, nil
+		}
+	}
+	return var_const.DockerInfo{}, fmt.Errorf("tarea de containerd %s no encontrada en %v", id, r.namespaces)
+}
+
+// Remove detiene la tarea con SIGKILL y elimina la tarea y el contenedor.
+func (r *containerdRuntime) Remove(id string) error {
+	var lastErr error
+	for _, ns := range r.namespaceOf(id) {
+		// kill y delete fallan si la tarea ya terminó o fue detenida (Stop); se continúa
+		_, _ = r.ctr(ns, "tasks", "kill", "--signal", "SIGKILL", id)
+		_, _ = r.ctr(ns, "tasks", "delete", "--force", id)
+		if _, err := r.ctr(ns, "containers", "delete", id); err != nil {
+			lastErr = err
+			continue
+		}
+
+		r.cacheLock.Lock()
+		delete(r.cache, id)
+		r.cacheLock.Unlock()
+		return nil
+	}
+	return fmt.Errorf("no se pudo eliminar %s: %w", id, lastErr)
+}
+
+// namespaceOf retorna el namespace conocido del contenedor o todos los configurados.
+func (r *containerdRuntime) namespaceOf(id string) []string {
+	r.cacheLock.Lock()
+	entry, ok := r.cache[id]
+	r.cacheLock.Unlock()
+	if ok && entry.info.Namespace != "" {
+		return []string{entry.info.Namespace}
+	}
+	return r.namespaces
+}
+
+// Throttle no está disponible en containerd: ctr no expone una
+// actualización de recursos, por lo que el daemon escribe los límites en
+// el cgroup resuelto del contenedor.
+func (r *containerdRuntime) Throttle(id string, cpus float64, memoryBytes int64) error {
+	return ErrNoUpdateAPI
+}
+
+func (r *containerdRuntime) Pause(id string) error {
+	var lastErr error
+	for _, ns := range r.namespaceOf(id) {
+		if _, err := r.ctr(ns, "tasks", "pause", id); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+	return lastErr
+}
+
+// Stop envía SIGTERM a la tarea; si no termina a tiempo se fuerza SIGKILL.
+func (r *containerdRuntime) Stop(id string) error {
+	var lastErr error
+	for _, ns := range r.namespaceOf(id) {
+		if _, err := r.ctr(ns, "tasks", "kill", "--signal", "SIGTERM", id); err != nil {
+			lastErr = err
+			continue
+		}
+		deadline := time.Now().Add(STOP_TIMEOUT_SEC * time.Second)
+		for time.Now().Before(deadline) {
+			out, err := r.ctr(ns, "tasks", "ls")
+			if err != nil {
+				break
+			}
+			if status, ok := taskStatus(out, id); !ok || status == "STOPPED" {
+				break
+			}
+			time.Sleep(500 * time.Millisecond)
+		}
+		_, _ = r.ctr(ns, "tasks", "kill", "--signal", "SIGKILL", id)
+		// La tarea se elimina, el contenedor (y su snapshot) se conserva
+		_, _ = r.ctr(ns, "tasks", "delete", id)
+		return nil
+	}
+	return lastErr
+}
+
+// taskStatus busca la línea de la tarea id en la salida de `ctr tasks ls`
+// (ver PidMap) y retorna su columna STATUS. ok es false si la tarea no
+// aparece.
+func taskStatus(out, id string) (status string, ok bool) {
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == id {
+			return fields[2], true
+		}
+	}
+	return "", false
+}
+
+func (r *containerdRuntime) ShimContainerID(p var_const.ProcProcess) string {
+	if !isContainerdShim(p) {
+		return ""
+	}
+	return ExtractContainerID(p.Cmdline)
+}
+
+// CgroupDirs usa la ruta declarada en la especificación OCI del contenedor.
+// Con el driver systemd la ruta tiene la forma "slice:prefijo:nombre".
+func (r *containerdRuntime) CgroupDirs(info var_const.DockerInfo) []string {
+	r.cacheLock.Lock()
+	entry, ok := r.cache[info.ContainerID]
+	r.cacheLock.Unlock()
+
+	var dirs []string
+	if ok && entry.cgroupsPath != "" {
+		dirs = append(dirs, cgroupsPathToDir(entry.cgroupsPath))
+	}
+	// Rutas por defecto del driver cgroupfs y systemd de containerd
+	dirs = append(dirs,
+		fmt.Sprintf("%s/%s", info.Namespace, info.ContainerID),
+		fmt.Sprintf("system.slice/containerd-%s.scope", info.ContainerID),
+	)
+	return dirs
+}
+
+// cgroupsPathToDir convierte el cgroupsPath de OCI a un directorio relativo.
+//
+//	"/k8s.io/<id>"                              -> "k8s.io/<id>"
+//	"kubepods-besteffort-pod1.slice:cri-containerd:<id>"
+//	  -> "kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod1.slice/cri-containerd-<id>.scope"
+func cgroupsPathToDir(p string) string {
+	parts := strings.Split(p, ":")
+	if len(parts) != 3 {
+		return strings.TrimPrefix(p, "/")
+	}
+	return expandSlice(parts[0]) + "/" + parts[1] + "-" + parts[2] + ".scope"
+}
+
+// expandSlice reproduce la jerarquía de systemd: "a-b-c.slice" vive en
+// "a.slice/a-b.slice/a-b-c.slice".
+func expandSlice(slice string) string {
+	name := strings.TrimSuffix(slice, ".slice")
+	if name == "" || name == "-" {
+		return ""
+	}
+	parts := strings.Split(name, "-")
+	dirs := make([]string, 0, len(parts))
+	for i := range parts {
+		dirs = append(dirs, strings.Join(parts[:i+1], "-")+".slice")
+	}
+	return strings.Join(dirs, "/")
+}
@@ -0,0 +1,67 @@
+package cruntime
+
+import "testing"
+
+func TestCgroupsPathToDir(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"/k8s.io/abc", "k8s.io/abc"},
+		{"default/abc", "default/abc"},
+		{"kubepods-besteffort-pod1.slice:cri-containerd:abc",
+			"kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod1.slice/cri-containerd-abc.scope"},
+		{"system.slice:containerd:abc", "system.slice/containerd-abc.scope"},
+	}
+	for _, tt := range tests {
+		if got := cgroupsPathToDir(tt.path); got != tt.want {
+			t.Errorf("cgroupsPathToDir(%q) = %q, se esperaba %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestExpandSlice(t *testing.T) {
+	tests := []struct {
+		slice, want string
+	}{
+		{"system.slice", "system.slice"},
+		{"kubepods.slice", "kubepods.slice"},
+		{"kubepods-burstable.slice", "kubepods.slice/kubepods-burstable.slice"},
+		{"a-b-c.slice", "a.slice/a-b.slice/a-b-c.slice"},
+		{"-.slice", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := expandSlice(tt.slice); got != tt.want {
+			t.Errorf("expandSlice(%q) = %q, se esperaba %q", tt.slice, got, tt.want)
+		}
+	}
+}
+
+func TestTaskStatus(t *testing.T) {
+	out := "TASK    PID     STATUS\n" +
+		"abc     1234    RUNNING\n" +
+		"abcdef  0       STOPPED\n" +
+		"paused  99      PAUSED\n"
+	tests := []struct {
+		id     string
+		status string
+		ok     bool
+	}{
+		{"abc", "RUNNING", true},
+		{"abcdef", "STOPPED", true},
+		{"paused", "PAUSED", true},
+		// Sin coincidencia por prefijo ni con el encabezado
+		{"ab", "", false},
+		{"PID", "", false},
+		{"otro", "", false},
+	}
+	for _, tt := range tests {
+		status, ok := taskStatus(out, tt.id)
+		if status != tt.status || ok != tt.ok {
+			t.Errorf("taskStatus(%q) = %q, %v; se esperaba %q, %v", tt.id, status, ok, tt.status, tt.ok)
+		}
+	}
+	if _, ok := taskStatus("", "abc"); ok {
+		t.Error("taskStatus con salida vacía encontró la tarea")
+	}
+}
@@ -0,0 +1,231 @@
+package cruntime
+
+import (
+	"fmt"
+	"so1-daemon/dockerapi"
+	"so1-daemon/var_const"
+	"strings"
+	"sync"
+)
+
+// engineRuntime implementa ContainerRuntime para motores compatibles con el
+// Docker Engine API: Docker y Podman (socket de compatibilidad).
+type engineRuntime struct {
+	name   string
+	client dockerapi.Client
+
+	// shimNames son los nombres (comm) de los procesos intermedios del motor
+	shimNames []string
+	// cgroupFmts son los formatos de los directorios de cgroup (%s = ID)
+	cgroupFmts []string
+
+	// cache guarda el resultado de inspect por Container ID. El PID
+	// principal, la imagen y el nombre no cambian mientras el contenedor
+	// siga en ejecución, por lo que solo se inspeccionan los nuevos y los
+	// que se reiniciaron con el mismo ID (su PID principal cambió).
+	cache     map[string]engineEntry
+	cacheLock sync.Mutex
+}
+
+// engineEntry es un contenedor inspeccionado junto con el inicio de su PID
+// principal (starttime de /proc/<pid>/stat) al momento de inspeccionarlo.
+type engineEntry struct {
+	info      var_const.DockerInfo
+	startTime uint64
+}
+
+// stale indica si el PID principal ya no es el inspeccionado: terminó o
+// fue reutilizado porque el contenedor se reinició (restart policy o
+// docker restart).
+func (e engineEntry) stale() bool {
+	st, err := pidStartTime(e.info.Pid)
+	return err != nil || st != e.startTime
+}
+
+// NewDocker crea el runtime de Docker sobre el socket indicado.
+func NewDocker(socket string) ContainerRuntime {
+	return NewEngine("docker", dockerapi.NewClient(socket))
+}
+
+// NewEngine crea un runtime compatible con el Engine API a partir de un
+// cliente ya construido (útil para apuntar a un servidor falso).
+func NewEngine(name string, client dockerapi.Client) ContainerRuntime {
+	r := &engineRuntime{
+		name:   name,
+		client: client,
+		cache:  make(map[string]engineEntry),
+	}
+	switch name {
+	case "podman":
+		r.shimNames = []string{"conmon"}
+		r.cgroupFmts = []string{
+			"machine.slice/libpod-%s.scope",
+			"libpod_parent/libpod-%s",
+		}
+	default:
+		r.shimNames = []string{"containerd-shim"}
+		r.cgroupFmts = []string{
+			"docker/%s",
+			"system.slice/docker-%s.scope",
+		}
+	}
+	return r
+}
+
+// NewPodman crea el runtime de Podman usando su API compatible con Docker
+// (podman system service, por defecto /run/podman/podman.sock).
+func NewPodman(socket string) ContainerRuntime {
+	return NewEngine("podman", dockerapi.NewClient(socket))
+}
+
+func (r *engineRuntime) Name() string {
+	return r.name
+}
+
+// toDockerInfo convierte la respuesta de inspect al tipo usado por el daemon.
+func toDockerInfo(info dockerapi.ContainerJSON) var_const.DockerInfo {
+	return var_const.DockerInfo{
+		ContainerID: info.ID,
+		Image:       info.Config.Image,
+		Pid:         info.State.Pid,
+		Name:        info.Name,
+		Labels:      info.Config.Labels,
+	}
+}
+
+// PidMap lista los contenedores activos (una sola petición) e inspecciona
+// únicamente los que no estén en caché o cuyo PID principal cambió.
+func (r *engineRuntime) PidMap() (map[int]var_const.DockerInfo, error) {
+	list, err := r.client.List()
+	if err != nil {
+		return nil, err
+	}
+
+	r.cacheLock.Lock()
+	defer r.cacheLock.Unlock()
+
+	result := make(map[int]var_const.DockerInfo)
+	alive := make(map[string]bool, len(list))
+
+	for _, c := range list {
+		alive[c.ID] = true
+
+		entry, ok := r.cache[c.ID]
+		if !ok || entry.stale() {
+			raw, err := r.client.Inspect(c.ID)
+
+			// Si ocurre un error con este contenedor, se omite
+			// para no afectar el procesamiento del resto
+			if err != nil {
+				delete(r.cache, c.ID)
+				continue
+			}
+			if raw.State.Pid == 0 {
+				// Aún no tiene proceso (arrancando); se reintenta el siguiente ciclo
+				delete(r.cache, c.ID)
+				continue
+			}
+			entry = r.store(raw)
+		}
+
+		result[entry.info.Pid] = entry.info
+	}
+
+	// Descartar contenedores que ya no existen
+	for id := range r.cache {
+		if !alive[id] {
+			delete(r.cache, id)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *engineRuntime) InfoByID(id string) (var_const.DockerInfo, error) {
+	r.cacheLock.Lock()
+	defer r.cacheLock.Unlock()
+
+	for cid, entry := range r.cache {
+		if strings.HasPrefix(cid, id) && !entry.stale() {
+			return entry.info, nil
+		}
+	}
+
+	raw, err := r.client.Inspect(id)
+	if err != nil {
+		return var_const.DockerInfo{}, err
+	}
+	if raw.State.Pid == 0 {
+		return var_const.DockerInfo{}, fmt.Errorf("el contenedor %s no está en ejecución", id)
+	}
+
+	return r.store(raw).info, nil
+}
+
+// store guarda en caché el resultado de inspect. Debe llamarse con
+// cacheLock tomado.
+func (r *engineRuntime) store(raw dockerapi.ContainerJSON) engineEntry {
+	entry := engineEntry{info: toDockerInfo(raw)}
+	entry.startTime, _ = pidStartTime(entry.info.Pid)
+	r.cache[entry.info.ContainerID] = entry
+	return entry
+}
+
+func (r *engineRuntime) Remove(id string) error {
+	if err := r.client.Remove(id, true); err != nil {
+		return err
+	}
+	r.cacheLock.Lock()
+	delete(r.cache, id)
+	r.cacheLock.Unlock()
+	return nil
+}
+
+func (r *engineRuntime) Throttle(id string, cpus float64, memoryBytes int64) error {
+	res := dockerapi.Resources{
+		NanoCpus: int64(cpus * 1e9),
+		Memory:   memoryBytes,
+	}
+	if memoryBytes > 0 {
+		// Sin swap adicional: el límite aplica a memoria + swap
+		res.MemorySwap = memoryBytes
+	}
+	return r.client.Update(id, res)
+}
+
+func (r *engineRuntime) Pause(id string) error {
+	return r.client.Pause(id)
+}
+
+func (r *engineRuntime) Stop(id string) error {
+	if err := r.client.Stop(id, STOP_TIMEOUT_SEC); err != nil {
+		return err
+	}
+	// El contenedor deja de estar en ejecución: se descarta de la caché
+	r.cacheLock.Lock()
+	delete(r.cache, id)
+	r.cacheLock.Unlock()
+	return nil
+}
+
+func (r *engineRuntime) ShimContainerID(p var_const.ProcProcess) string {
+	for _, n := range r.shimNames {
+		if !strings.HasPrefix(p.Name, n) {
+			continue
+		}
+		if n == "conmon" {
+			// conmon -c <id> / --cid <id>
+			return flagValue(p.Cmdline, "-c", "--cid")
+		}
+		return ExtractContainerID(p.Cmdline)
+	}
+	return ""
+}
+
+func (r *engineRuntime) CgroupDirs(info var_const.DockerInfo) []string {
+	dirs := make([]string, 0, len(r.cgroupFmts))
+	for _, f := range r.cgroupFmts {
+		dirs = append(dirs, fmt.Sprintf(f, info.ContainerID))
+	}
+	return dirs
+}
@@ -0,0 +1,61 @@
+package cruntime
+
+import (
+	"os"
+	"os/exec"
+	"so1-daemon/dockerapi"
+	"testing"
+)
+
+// fakeClient responde List con un único contenedor e Inspect con el PID
+// indicado en pid.
+type fakeClient struct {
+	dockerapi.Client
+	pid      int
+	inspects int
+}
+
+func (f *fakeClient) List() ([]dockerapi.Container, error) {
+	return []dockerapi.Container{{ID: "abc"}}, nil
+}
+
+func (f *fakeClient) Inspect(id string) (dockerapi.ContainerJSON, error) {
+	f.inspects++
+	var info dockerapi.ContainerJSON
+	info.ID = id
+	info.State.Pid = f.pid
+	return info, nil
+}
+
+func TestPidMapReinspectsRestartedContainer(t *testing.T) {
+	// PID principal de la primera ejecución: un proceso que ya terminó
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skip("no se pudo ejecutar true:", err)
+	}
+	client := &fakeClient{pid: cmd.Process.Pid}
+	r := NewEngine("docker", client)
+
+	if _, err := r.PidMap(); err != nil {
+		t.Fatal(err)
+	}
+
+	// El contenedor se reinicia con el mismo ID y otro PID
+	client.pid = os.Getpid()
+	m, err := r.PidMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m[os.Getpid()]; !ok {
+		t.Fatalf("PidMap no refleja el nuevo PID: %v", m)
+	}
+
+	// Mientras el PID siga vivo no se vuelve a inspeccionar
+	inspects := client.inspects
+	if _, err := r.PidMap(); err != nil {
+		t.Fatal(err)
+	}
+	if client.inspects != inspects {
+		t.Fatalf("se inspeccionó de nuevo un contenedor sin cambios (%d > %d)", client.inspects, inspects)
+	}
+}
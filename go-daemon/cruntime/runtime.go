@@ -0,0 +1,110 @@
+package cruntime
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"so1-daemon/config"
+	"so1-daemon/var_const"
+	"strconv"
+	"strings"
+)
+
+// ContainerRuntime abstrae el motor de contenedores del host (Docker,
+// containerd o Podman). El daemon solo interactúa con los contenedores a
+// través de esta interfaz.
+type ContainerRuntime interface {
+	// Name retorna el nombre del runtime ("docker", "containerd", "podman").
+	Name() string
+	// PidMap retorna los contenedores en ejecución indexados por el PID
+	// de su proceso principal en el host.
+	PidMap() (map[int]var_const.DockerInfo, error)
+	// InfoByID retorna la información de un contenedor por su ID (o prefijo).
+	InfoByID(id string) (var_const.DockerInfo, error)
+	// Remove elimina el contenedor de forma forzada.
+	Remove(id string) error
+	// Throttle limita las CPUs y la memoria (bytes) del contenedor; los
+	// valores en 0 no se modifican. Retorna ErrNoUpdateAPI si el runtime
+	// no puede actualizar los recursos.
+	Throttle(id string, cpus float64, memoryBytes int64) error
+	// Pause congela los procesos del contenedor.
+	Pause(id string) error
+	// Stop detiene el contenedor sin eliminarlo.
+	Stop(id string) error
+	// ShimContainerID retorna el ID del contenedor que administra un proceso
+	// intermedio (containerd-shim, conmon) o "" si p no es un shim.
+	ShimContainerID(p var_const.ProcProcess) string
+	// CgroupDirs retorna los directorios candidatos del cgroup del contenedor,
+	// relativos a la raíz de la jerarquía (ej. "system.slice/docker-<id>.scope").
+	CgroupDirs(info var_const.DockerInfo) []string
+}
+
+// ErrNoUpdateAPI lo retorna Throttle en los runtimes sin API para
+// actualizar recursos (containerd); el límite se escribe entonces en el
+// cgroup del contenedor.
+var ErrNoUpdateAPI = errors.New("el runtime no permite actualizar recursos")
+
+// STOP_TIMEOUT_SEC es la espera antes de forzar SIGKILL al detener.
+const STOP_TIMEOUT_SEC = 10
+
+// New crea el runtime seleccionado en la configuración.
+func New(cfg *config.Config) (ContainerRuntime, error) {
+	switch cfg.Runtime {
+	case "docker":
+		return NewDocker(cfg.DockerSocket), nil
+	case "podman":
+		return NewPodman(cfg.PodmanSocket), nil
+	case "containerd":
+		return NewContainerd(cfg.ContainerdAddress, cfg.ContainerdNamespaces), nil
+	default:
+		return nil, fmt.Errorf("runtime desconocido: %q", cfg.Runtime)
+	}
+}
+
+// flagValue busca en una línea de comandos el valor que sigue a alguna de
+// las opciones indicadas (ej. " -id <valor>").
+func flagValue(cmdline string, names ...string) string {
+	fields := strings.Fields(cmdline)
+	for i := 0; i < len(fields)-1; i++ {
+		for _, n := range names {
+			if fields[i] == n {
+				return fields[i+1]
+			}
+		}
+	}
+	return ""
+}
+
+// ExtractContainerID busca la cadena "-id " en la línea de comandos
+// de un containerd-shim y devuelve el Container ID que le sigue.
+func ExtractContainerID(cmdline string) string {
+	return flagValue(cmdline, "-id")
+}
+
+// pidStartTime lee el inicio del proceso (campo 22 de /proc/<pid>/stat,
+// en ticks desde el arranque). Junto con el PID identifica al proceso
+// aunque el PID se reutilice.
+func pidStartTime(pid int) (uint64, error) {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, err
+	}
+	// comm (campo 2) puede contener espacios y paréntesis
+	i := bytes.LastIndexByte(b, ')')
+	if i < 0 {
+		return 0, fmt.Errorf("/proc/%d/stat: formato inesperado", pid)
+	}
+	fields := strings.Fields(string(b[i+1:]))
+	if len(fields) < 20 {
+		return 0, fmt.Errorf("/proc/%d/stat: formato inesperado", pid)
+	}
+	return strconv.ParseUint(fields[19], 10, 64)
+}
+
+// isContainerdShim indica si el proceso es un containerd-shim. El nombre
+// del kernel (comm) se trunca a 15 caracteres, por lo que
+// "containerd-shim-runc-v2" aparece como "containerd-shim".
+func isContainerdShim(p var_const.ProcProcess) bool {
+	return strings.HasPrefix(p.Name, "containerd-shim")
+}
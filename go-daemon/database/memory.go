@@ -0,0 +1,135 @@
+package database
+
+import (
+	"so1-daemon/config"
+	"so1-daemon/var_const"
+	"sync"
+	"time"
+)
+
+// memoryStore guarda las filas en memoria. No genera agregados; Maintain
+// solo aplica retention.raw. Útil para pruebas y para ejecutar el daemon
+// sin escribir en disco.
+type memoryStore struct {
+	lock       sync.RWMutex
+	sys        []var_const.SysSample
+	cores      []var_const.CoreSample
+	processes  []var_const.ProcessCountSample
+	procs      []var_const.ProcessSample
+	containers []var_const.ContainerSample
+	deletions  []var_const.DeletionRecord
+	actions    []var_const.ActionRecord
+}
+
+// NewMemoryStore crea un almacenamiento en memoria vacío.
+func NewMemoryStore() MetricsStore {
+	return &memoryStore{}
+}
+
+func (m *memoryStore) Name() string { return "memory" }
+
+func (m *memoryStore) Close() error { return nil }
+
+func (m *memoryStore) WriteTick(t *Tick) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.sys = append(m.sys, t.Sys...)
+	m.cores = append(m.cores, t.Cores...)
+	m.processes = append(m.processes, t.Processes...)
+	m.procs = append(m.procs, t.ProcessSamples...)
+	m.containers = append(m.containers, t.Containers...)
+	m.deletions = append(m.deletions, t.Deletions...)
+	m.actions = append(m.actions, t.Actions...)
+	return nil
+}
+
+// inRange recorre rows del más reciente al más antiguo (las filas se
+// agregan en orden) y retorna las que cumplen r y keep.
+func inRange[T any](rows []T, ts func(T) int64, r Range, keep func(T) bool) []T {
+	result := []T{}
+	for i := len(rows) - 1; i >= 0; i-- {
+		v := rows[i]
+		if (r.From > 0 && ts(v) < r.From) || (r.To > 0 && ts(v) > r.To) {
+			continue
+		}
+		if keep != nil && !keep(v) {
+			continue
+		}
+		result = append(result, v)
+		if r.Limit > 0 && len(result) == r.Limit {
+			break
+		}
+	}
+	return result
+}
+
+// trimBefore descarta las filas anteriores a cutoff.
+func trimBefore[T any](rows []T, ts func(T) int64, cutoff int64) []T {
+	i := 0
+	for i < len(rows) && ts(rows[i]) < cutoff {
+		i++
+	}
+	return append([]T(nil), rows[i:]...)
+}
+
+func (m *memoryStore) QuerySysMetrics(r Range) ([]var_const.SysSample, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return inRange(m.sys, func(v var_const.SysSample) int64 { return v.Ts }, r, nil), nil
+}
+
+func (m *memoryStore) QueryCpuCores(r Range) ([]var_const.CoreSample, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return inRange(m.cores, func(v var_const.CoreSample) int64 { return v.Ts }, r, nil), nil
+}
+
+func (m *memoryStore) QueryContainers(containerID string, r Range) ([]var_const.ContainerSample, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return inRange(m.containers, func(v var_const.ContainerSample) int64 { return v.Ts }, r, func(v var_const.ContainerSample) bool {
+		return containerID == "" || v.ContainerID == containerID
+	}), nil
+}
+
+func (m *memoryStore) QueryProcessCount(r Range) ([]var_const.ProcessCountSample, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return inRange(m.processes, func(v var_const.ProcessCountSample) int64 { return v.Ts }, r, nil), nil
+}
+
+func (m *memoryStore) QueryProcesses(pid int, r Range) ([]var_const.ProcessSample, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return inRange(m.procs, func(v var_const.ProcessSample) int64 { return v.Ts }, r, func(v var_const.ProcessSample) bool {
+		return pid == 0 || v.Pid == pid
+	}), nil
+}
+
+func (m *memoryStore) QueryDeletions(r Range) ([]var_const.DeletionRecord, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return inRange(m.deletions, func(v var_const.DeletionRecord) int64 { return v.Ts }, r, nil), nil
+}
+
+func (m *memoryStore) QueryActions(r Range) ([]var_const.ActionRecord, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return inRange(m.actions, func(v var_const.ActionRecord) int64 { return v.Ts }, r, nil), nil
+}
+
+func (m *memoryStore) Maintain(r config.Retention, now time.Time) error {
+	if r.Raw.Duration <= 0 {
+		return nil
+	}
+	cutoff := now.Add(-r.Raw.Duration).Unix()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.sys = trimBefore(m.sys, func(v var_const.SysSample) int64 { return v.Ts }, cutoff)
+	m.cores = trimBefore(m.cores, func(v var_const.CoreSample) int64 { return v.Ts }, cutoff)
+	m.processes = trimBefore(m.processes, func(v var_const.ProcessCountSample) int64 { return v.Ts }, cutoff)
+	m.procs = trimBefore(m.procs, func(v var_const.ProcessSample) int64 { return v.Ts }, cutoff)
+	m.containers = trimBefore(m.containers, func(v var_const.ContainerSample) int64 { return v.Ts }, cutoff)
+	return nil
+}
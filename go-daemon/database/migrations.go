@@ -0,0 +1,173 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"so1-daemon/utils"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// MIGRATIONS_DIR contiene un subdirectorio por motor (sqlite, postgres)
+// con las migraciones numeradas del esquema (NNNN_descripcion.sql). Solo
+// existen migraciones hacia adelante.
+var MIGRATIONS_DIR = utils.ABSPATH("../database/migrations")
+
+var migrationName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.sql$`)
+
+// Migration es un archivo de migración del esquema.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// MigrationStatus describe una migración y si ya fue aplicada.
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// LoadMigrations lee las migraciones de dir ordenadas por versión. Los
+// números deben ser únicos y mayores que 0.
+func LoadMigrations(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("leer migraciones: %v", err)
+	}
+
+	var list []Migration
+	seen := make(map[int]string)
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".sql" {
+			continue
+		}
+		m := migrationName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migración %s: el nombre debe tener el formato NNNN_descripcion.sql", e.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		if version < 1 {
+			return nil, fmt.Errorf("migración %s: la versión debe ser mayor que 0", e.Name())
+		}
+		if prev, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migraciones %s y %s tienen la misma versión %d", prev, e.Name(), version)
+		}
+		seen[version] = e.Name()
+
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("leer migración %s: %v", e.Name(), err)
+		}
+		list = append(list, Migration{Version: version, Name: m[2], SQL: string(b)})
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// ensureVersionTable crea la tabla schema_version si no existe.
+func (s *sqlStore) ensureVersionTable() error {
+	_, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (
+  version INTEGER PRIMARY KEY,
+  name TEXT NOT NULL,
+  applied_at BIGINT NOT NULL
+)`)
+	return err
+}
+
+// appliedVersions retorna las versiones registradas en schema_version.
+func (s *sqlStore) appliedVersions() (map[int]int64, error) {
+	rows, err := s.db.Query("SELECT version, applied_at FROM schema_version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]int64)
+	for rows.Next() {
+		var v int
+		var ts int64
+		if err := rows.Scan(&v, &ts); err != nil {
+			return nil, err
+		}
+		applied[v] = ts
+	}
+	return applied, rows.Err()
+}
+
+// MigrationsStatus retorna todas las migraciones conocidas indicando
+// cuáles ya fueron aplicadas a la base abierta.
+func (s *sqlStore) MigrationsStatus() ([]MigrationStatus, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	list, err := LoadMigrations(s.migrations)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureVersionTable(); err != nil {
+		return nil, fmt.Errorf("crear schema_version: %v", err)
+	}
+	applied, err := s.appliedVersions()
+	if err != nil {
+		return nil, fmt.Errorf("leer schema_version: %v", err)
+	}
+
+	status := make([]MigrationStatus, 0, len(list))
+	for _, m := range list {
+		ms := MigrationStatus{Migration: m}
+		if ts, ok := applied[m.Version]; ok {
+			ms.Applied = true
+			ms.AppliedAt = time.Unix(ts, 0)
+		}
+		status = append(status, ms)
+	}
+	return status, nil
+}
+
+// ApplyMigrations aplica en orden las migraciones pendientes. Cada una se
+// ejecuta en su propia transacción junto con su registro en
+// schema_version: si falla, la base queda en la versión anterior y no se
+// aplican las siguientes. Retorna las migraciones aplicadas.
+func (s *sqlStore) ApplyMigrations() ([]Migration, error) {
+	status, err := s.MigrationsStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var done []Migration
+	for _, ms := range status {
+		if ms.Applied {
+			continue
+		}
+		if err := s.applyMigration(ms.Migration); err != nil {
+			return done, fmt.Errorf("migración %04d_%s: %v", ms.Version, ms.Name, err)
+		}
+		done = append(done, ms.Migration)
+	}
+	return done, nil
+}
+
+func (s *sqlStore) applyMigration(m Migration) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(m.SQL); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(s.q("INSERT INTO schema_version(version, name, applied_at) VALUES(?,?,?)"), m.Version, m.Name, time.Now().Unix()); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
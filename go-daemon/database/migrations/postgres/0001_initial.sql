@@ -0,0 +1,89 @@
+-- Esquema equivalente a las migraciones 0001-0005 de SQLite. Cada fila
+-- lleva el host de origen para centralizar varios daemons en una base.
+
+CREATE TABLE IF NOT EXISTS containers (
+  id BIGSERIAL PRIMARY KEY,
+  host TEXT NOT NULL DEFAULT '',
+  container_id TEXT,
+  pid INTEGER,
+  image TEXT,
+  cpu_pct DOUBLE PRECISION,
+  mem_pct DOUBLE PRECISION,
+  ts BIGINT
+);
+
+CREATE TABLE IF NOT EXISTS deletions (
+  id BIGSERIAL PRIMARY KEY,
+  host TEXT NOT NULL DEFAULT '',
+  container_id TEXT,
+  reason TEXT,
+  ts BIGINT
+);
+
+CREATE TABLE IF NOT EXISTS sys_metrics (
+  id BIGSERIAL PRIMARY KEY,
+  host TEXT NOT NULL DEFAULT '',
+  mem_total_kb BIGINT,
+  mem_free_kb BIGINT,
+  mem_used_kb BIGINT,
+  ts BIGINT
+);
+
+CREATE TABLE IF NOT EXISTS process_count (
+  id BIGSERIAL PRIMARY KEY,
+  host TEXT NOT NULL DEFAULT '',
+  total INTEGER,
+  ts BIGINT
+);
+
+CREATE TABLE IF NOT EXISTS actions (
+  id BIGSERIAL PRIMARY KEY,
+  host TEXT NOT NULL DEFAULT '',
+  container_id TEXT,
+  image TEXT,
+  class TEXT,
+  action TEXT,
+  reason TEXT,
+  cpu_pct DOUBLE PRECISION,
+  mem_pct DOUBLE PRECISION,
+  dry_run BOOLEAN,
+  ts BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS idx_containers_host_ts ON containers(host, ts);
+CREATE INDEX IF NOT EXISTS idx_deletions_host_ts ON deletions(host, ts);
+CREATE INDEX IF NOT EXISTS idx_sys_metrics_host_ts ON sys_metrics(host, ts);
+CREATE INDEX IF NOT EXISTS idx_process_count_host_ts ON process_count(host, ts);
+CREATE INDEX IF NOT EXISTS idx_actions_host_ts ON actions(host, ts);
+
+CREATE TABLE IF NOT EXISTS containers_1m (
+  host TEXT NOT NULL DEFAULT '',
+  container_id TEXT NOT NULL,
+  image TEXT,
+  bucket_ts BIGINT NOT NULL,
+  samples INTEGER NOT NULL,
+  cpu_min DOUBLE PRECISION, cpu_avg DOUBLE PRECISION, cpu_max DOUBLE PRECISION, cpu_p95 DOUBLE PRECISION,
+  mem_min DOUBLE PRECISION, mem_avg DOUBLE PRECISION, mem_max DOUBLE PRECISION, mem_p95 DOUBLE PRECISION,
+  PRIMARY KEY (container_id, bucket_ts)
+);
+
+CREATE TABLE IF NOT EXISTS containers_1h (
+  host TEXT NOT NULL DEFAULT '',
+  container_id TEXT NOT NULL,
+  image TEXT,
+  bucket_ts BIGINT NOT NULL,
+  samples INTEGER NOT NULL,
+  cpu_min DOUBLE PRECISION, cpu_avg DOUBLE PRECISION, cpu_max DOUBLE PRECISION, cpu_p95 DOUBLE PRECISION,
+  mem_min DOUBLE PRECISION, mem_avg DOUBLE PRECISION, mem_max DOUBLE PRECISION, mem_p95 DOUBLE PRECISION,
+  PRIMARY KEY (container_id, bucket_ts)
+);
+
+CREATE INDEX IF NOT EXISTS idx_containers_1m_ts ON containers_1m(bucket_ts);
+CREATE INDEX IF NOT EXISTS idx_containers_1h_ts ON containers_1h(bucket_ts);
+
+CREATE TABLE IF NOT EXISTS rollup_state (
+  name TEXT NOT NULL,
+  host TEXT NOT NULL DEFAULT '',
+  last_ts BIGINT NOT NULL,
+  PRIMARY KEY (name, host)
+);
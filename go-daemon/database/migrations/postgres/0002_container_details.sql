@@ -0,0 +1,11 @@
+-- Detalle de cada muestra de contenedor y decisión tomada en el ciclo.
+
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS name TEXT;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS class TEXT;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS state TEXT;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS rss_kb BIGINT;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS vsz_kb BIGINT;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS decision TEXT;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_containers_decision_ts ON containers(decision, ts);
@@ -0,0 +1,18 @@
+-- Instantáneas por proceso del host (top-N de PROC_SYS por ciclo).
+
+CREATE TABLE IF NOT EXISTS processes (
+  id BIGSERIAL PRIMARY KEY,
+  host TEXT NOT NULL DEFAULT '',
+  pid INTEGER,
+  name TEXT,
+  cmdline TEXT,
+  rss_kb BIGINT,
+  vsz_kb BIGINT,
+  mem_pct DOUBLE PRECISION,
+  state TEXT,
+  cpu_pct DOUBLE PRECISION,
+  ts BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS idx_processes_ts ON processes(ts);
+CREATE INDEX IF NOT EXISTS idx_processes_pid_ts ON processes(pid, ts);
@@ -0,0 +1,9 @@
+-- Conteo de procesos por estado del kernel (R, S, D, T, Z, I y otros).
+
+ALTER TABLE process_count ADD COLUMN IF NOT EXISTS running INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE process_count ADD COLUMN IF NOT EXISTS sleeping INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE process_count ADD COLUMN IF NOT EXISTS disk_sleep INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE process_count ADD COLUMN IF NOT EXISTS stopped INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE process_count ADD COLUMN IF NOT EXISTS zombie INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE process_count ADD COLUMN IF NOT EXISTS idle INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE process_count ADD COLUMN IF NOT EXISTS other INTEGER NOT NULL DEFAULT 0;
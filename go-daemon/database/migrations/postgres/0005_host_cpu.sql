@@ -0,0 +1,24 @@
+-- Uso de CPU del host (/proc/stat) junto con la memoria, y uso por núcleo.
+-- Las columnas de CPU quedan en NULL en el primer ciclo (sin lectura previa).
+
+ALTER TABLE sys_metrics ADD COLUMN IF NOT EXISTS cpu_busy_pct DOUBLE PRECISION;
+ALTER TABLE sys_metrics ADD COLUMN IF NOT EXISTS cpu_user_pct DOUBLE PRECISION;
+ALTER TABLE sys_metrics ADD COLUMN IF NOT EXISTS cpu_system_pct DOUBLE PRECISION;
+ALTER TABLE sys_metrics ADD COLUMN IF NOT EXISTS cpu_idle_pct DOUBLE PRECISION;
+ALTER TABLE sys_metrics ADD COLUMN IF NOT EXISTS cpu_iowait_pct DOUBLE PRECISION;
+ALTER TABLE sys_metrics ADD COLUMN IF NOT EXISTS cpu_steal_pct DOUBLE PRECISION;
+
+CREATE TABLE IF NOT EXISTS cpu_cores (
+  id BIGSERIAL PRIMARY KEY,
+  host TEXT NOT NULL DEFAULT '',
+  cpu INTEGER,
+  busy_pct DOUBLE PRECISION,
+  user_pct DOUBLE PRECISION,
+  system_pct DOUBLE PRECISION,
+  idle_pct DOUBLE PRECISION,
+  iowait_pct DOUBLE PRECISION,
+  steal_pct DOUBLE PRECISION,
+  ts BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS idx_cpu_cores_ts ON cpu_cores(ts);
@@ -0,0 +1,19 @@
+-- Recursos del cgroup de cada contenedor (memoria en bytes, E/S acumulada,
+-- PIDs y promedios avg10 de PSI). NULL si no se pudo leer el cgroup.
+
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS cgroup_version INTEGER;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS mem_current_bytes BIGINT;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS mem_working_set_bytes BIGINT;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS mem_max_bytes BIGINT;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS mem_anon_bytes BIGINT;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS mem_file_bytes BIGINT;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS io_read_bytes BIGINT;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS io_write_bytes BIGINT;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS io_read_ops BIGINT;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS io_write_ops BIGINT;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS pids_current BIGINT;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS cpu_psi_some_avg10 DOUBLE PRECISION;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS mem_psi_some_avg10 DOUBLE PRECISION;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS mem_psi_full_avg10 DOUBLE PRECISION;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS io_psi_some_avg10 DOUBLE PRECISION;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS io_psi_full_avg10 DOUBLE PRECISION;
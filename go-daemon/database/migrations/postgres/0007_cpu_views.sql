@@ -0,0 +1,7 @@
+-- %CPU de los contenedores respecto de los núcleos del host y de su cuota
+-- de CPU, y la cuota en CPUs (NULL si no se pudo leer el cgroup, 0 sin
+-- cuota).
+
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS cpu_host_pct DOUBLE PRECISION;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS cpu_quota_pct DOUBLE PRECISION;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS cpu_limit DOUBLE PRECISION;
@@ -0,0 +1,4 @@
+-- Cantidad de tareas sumadas en rss_kb y vsz_kb de cada contenedor (NULL
+-- en las filas anteriores, que solo tienen el proceso principal).
+
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS tasks INTEGER;
@@ -0,0 +1,8 @@
+-- La clave de los agregados incluye el host: con (container_id, bucket_ts)
+-- dos hosts con el mismo id de contenedor se sobrescribían entre sí.
+
+ALTER TABLE containers_1m DROP CONSTRAINT IF EXISTS containers_1m_pkey;
+ALTER TABLE containers_1m ADD PRIMARY KEY (host, container_id, bucket_ts);
+
+ALTER TABLE containers_1h DROP CONSTRAINT IF EXISTS containers_1h_pkey;
+ALTER TABLE containers_1h ADD PRIMARY KEY (host, container_id, bucket_ts);
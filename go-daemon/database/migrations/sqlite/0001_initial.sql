@@ -1,3 +1,6 @@
+-- Esquema original (schema.sql). Usa IF NOT EXISTS para poder adoptar
+-- bases creadas antes de existir las migraciones.
+
 CREATE TABLE IF NOT EXISTS containers (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
   container_id TEXT,
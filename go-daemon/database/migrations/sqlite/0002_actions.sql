@@ -0,0 +1,14 @@
+-- Acciones de la escalera de sanciones y decisiones del modo dry-run.
+
+CREATE TABLE IF NOT EXISTS actions (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  container_id TEXT,
+  image TEXT,
+  class TEXT,
+  action TEXT,
+  reason TEXT,
+  cpu_pct REAL,
+  mem_pct REAL,
+  dry_run INTEGER,
+  ts INTEGER
+);
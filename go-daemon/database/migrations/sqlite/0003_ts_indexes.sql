@@ -0,0 +1,7 @@
+-- Índices por tiempo para las consultas de Grafana y de la API HTTP.
+
+CREATE INDEX IF NOT EXISTS idx_containers_ts ON containers(ts);
+CREATE INDEX IF NOT EXISTS idx_deletions_ts ON deletions(ts);
+CREATE INDEX IF NOT EXISTS idx_sys_metrics_ts ON sys_metrics(ts);
+CREATE INDEX IF NOT EXISTS idx_process_count_ts ON process_count(ts);
+CREATE INDEX IF NOT EXISTS idx_actions_ts ON actions(ts);
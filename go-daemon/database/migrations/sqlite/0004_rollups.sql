@@ -0,0 +1,31 @@
+-- Agregados de uso por contenedor (1 minuto y 1 hora) generados por el
+-- mantenimiento periódico a partir de la tabla containers.
+
+CREATE TABLE IF NOT EXISTS containers_1m (
+  container_id TEXT NOT NULL,
+  image TEXT,
+  bucket_ts INTEGER NOT NULL,
+  samples INTEGER NOT NULL,
+  cpu_min REAL, cpu_avg REAL, cpu_max REAL, cpu_p95 REAL,
+  mem_min REAL, mem_avg REAL, mem_max REAL, mem_p95 REAL,
+  PRIMARY KEY (container_id, bucket_ts)
+);
+
+CREATE TABLE IF NOT EXISTS containers_1h (
+  container_id TEXT NOT NULL,
+  image TEXT,
+  bucket_ts INTEGER NOT NULL,
+  samples INTEGER NOT NULL,
+  cpu_min REAL, cpu_avg REAL, cpu_max REAL, cpu_p95 REAL,
+  mem_min REAL, mem_avg REAL, mem_max REAL, mem_p95 REAL,
+  PRIMARY KEY (container_id, bucket_ts)
+);
+
+CREATE INDEX IF NOT EXISTS idx_containers_1m_ts ON containers_1m(bucket_ts);
+CREATE INDEX IF NOT EXISTS idx_containers_1h_ts ON containers_1h(bucket_ts);
+
+-- Fin (exclusivo) del último bucket agregado por cada tabla de rollup
+CREATE TABLE IF NOT EXISTS rollup_state (
+  name TEXT PRIMARY KEY,
+  last_ts INTEGER NOT NULL
+);
@@ -0,0 +1,21 @@
+-- Columna host para identificar el origen de cada fila (mismo esquema que
+-- PostgreSQL). Las filas anteriores quedan con host = ''.
+
+ALTER TABLE containers ADD COLUMN host TEXT NOT NULL DEFAULT '';
+ALTER TABLE deletions ADD COLUMN host TEXT NOT NULL DEFAULT '';
+ALTER TABLE sys_metrics ADD COLUMN host TEXT NOT NULL DEFAULT '';
+ALTER TABLE process_count ADD COLUMN host TEXT NOT NULL DEFAULT '';
+ALTER TABLE actions ADD COLUMN host TEXT NOT NULL DEFAULT '';
+ALTER TABLE containers_1m ADD COLUMN host TEXT NOT NULL DEFAULT '';
+ALTER TABLE containers_1h ADD COLUMN host TEXT NOT NULL DEFAULT '';
+
+-- El avance de los rollups es por host
+CREATE TABLE rollup_state_new (
+  name TEXT NOT NULL,
+  host TEXT NOT NULL DEFAULT '',
+  last_ts INTEGER NOT NULL,
+  PRIMARY KEY (name, host)
+);
+INSERT INTO rollup_state_new(name, host, last_ts) SELECT name, '', last_ts FROM rollup_state;
+DROP TABLE rollup_state;
+ALTER TABLE rollup_state_new RENAME TO rollup_state;
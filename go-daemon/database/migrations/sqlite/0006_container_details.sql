@@ -0,0 +1,11 @@
+-- Detalle de cada muestra de contenedor y decisión tomada en el ciclo.
+
+ALTER TABLE containers ADD COLUMN name TEXT;
+ALTER TABLE containers ADD COLUMN class TEXT;
+ALTER TABLE containers ADD COLUMN state TEXT;
+ALTER TABLE containers ADD COLUMN rss_kb INTEGER;
+ALTER TABLE containers ADD COLUMN vsz_kb INTEGER;
+ALTER TABLE containers ADD COLUMN decision TEXT;
+ALTER TABLE containers ADD COLUMN reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_containers_decision_ts ON containers(decision, ts);
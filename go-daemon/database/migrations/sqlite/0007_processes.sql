@@ -0,0 +1,18 @@
+-- Instantáneas por proceso del host (top-N de PROC_SYS por ciclo).
+
+CREATE TABLE IF NOT EXISTS processes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  host TEXT NOT NULL DEFAULT '',
+  pid INTEGER,
+  name TEXT,
+  cmdline TEXT,
+  rss_kb INTEGER,
+  vsz_kb INTEGER,
+  mem_pct REAL,
+  state TEXT,
+  cpu_pct REAL,
+  ts INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_processes_ts ON processes(ts);
+CREATE INDEX IF NOT EXISTS idx_processes_pid_ts ON processes(pid, ts);
@@ -0,0 +1,9 @@
+-- Conteo de procesos por estado del kernel (R, S, D, T, Z, I y otros).
+
+ALTER TABLE process_count ADD COLUMN running INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE process_count ADD COLUMN sleeping INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE process_count ADD COLUMN disk_sleep INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE process_count ADD COLUMN stopped INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE process_count ADD COLUMN zombie INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE process_count ADD COLUMN idle INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE process_count ADD COLUMN other INTEGER NOT NULL DEFAULT 0;
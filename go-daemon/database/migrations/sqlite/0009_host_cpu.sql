@@ -0,0 +1,24 @@
+-- Uso de CPU del host (/proc/stat) junto con la memoria, y uso por núcleo.
+-- Las columnas de CPU quedan en NULL en el primer ciclo (sin lectura previa).
+
+ALTER TABLE sys_metrics ADD COLUMN cpu_busy_pct REAL;
+ALTER TABLE sys_metrics ADD COLUMN cpu_user_pct REAL;
+ALTER TABLE sys_metrics ADD COLUMN cpu_system_pct REAL;
+ALTER TABLE sys_metrics ADD COLUMN cpu_idle_pct REAL;
+ALTER TABLE sys_metrics ADD COLUMN cpu_iowait_pct REAL;
+ALTER TABLE sys_metrics ADD COLUMN cpu_steal_pct REAL;
+
+CREATE TABLE IF NOT EXISTS cpu_cores (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  host TEXT NOT NULL DEFAULT '',
+  cpu INTEGER,
+  busy_pct REAL,
+  user_pct REAL,
+  system_pct REAL,
+  idle_pct REAL,
+  iowait_pct REAL,
+  steal_pct REAL,
+  ts INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_cpu_cores_ts ON cpu_cores(ts);
@@ -0,0 +1,19 @@
+-- Recursos del cgroup de cada contenedor (memoria en bytes, E/S acumulada,
+-- PIDs y promedios avg10 de PSI). NULL si no se pudo leer el cgroup.
+
+ALTER TABLE containers ADD COLUMN cgroup_version INTEGER;
+ALTER TABLE containers ADD COLUMN mem_current_bytes INTEGER;
+ALTER TABLE containers ADD COLUMN mem_working_set_bytes INTEGER;
+ALTER TABLE containers ADD COLUMN mem_max_bytes INTEGER;
+ALTER TABLE containers ADD COLUMN mem_anon_bytes INTEGER;
+ALTER TABLE containers ADD COLUMN mem_file_bytes INTEGER;
+ALTER TABLE containers ADD COLUMN io_read_bytes INTEGER;
+ALTER TABLE containers ADD COLUMN io_write_bytes INTEGER;
+ALTER TABLE containers ADD COLUMN io_read_ops INTEGER;
+ALTER TABLE containers ADD COLUMN io_write_ops INTEGER;
+ALTER TABLE containers ADD COLUMN pids_current INTEGER;
+ALTER TABLE containers ADD COLUMN cpu_psi_some_avg10 REAL;
+ALTER TABLE containers ADD COLUMN mem_psi_some_avg10 REAL;
+ALTER TABLE containers ADD COLUMN mem_psi_full_avg10 REAL;
+ALTER TABLE containers ADD COLUMN io_psi_some_avg10 REAL;
+ALTER TABLE containers ADD COLUMN io_psi_full_avg10 REAL;
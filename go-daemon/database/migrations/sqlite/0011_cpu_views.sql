@@ -0,0 +1,7 @@
+-- %CPU de los contenedores respecto de los núcleos del host y de su cuota
+-- de CPU, y la cuota en CPUs (NULL si no se pudo leer el cgroup, 0 sin
+-- cuota).
+
+ALTER TABLE containers ADD COLUMN cpu_host_pct REAL;
+ALTER TABLE containers ADD COLUMN cpu_quota_pct REAL;
+ALTER TABLE containers ADD COLUMN cpu_limit REAL;
@@ -0,0 +1,39 @@
+-- La clave de los agregados incluye el host: con (container_id, bucket_ts)
+-- dos hosts con el mismo id de contenedor se sobrescribían entre sí.
+-- SQLite no permite cambiar la clave primaria, se reconstruyen las tablas.
+
+CREATE TABLE containers_1m_new (
+  host TEXT NOT NULL DEFAULT '',
+  container_id TEXT NOT NULL,
+  image TEXT,
+  bucket_ts INTEGER NOT NULL,
+  samples INTEGER NOT NULL,
+  cpu_min REAL, cpu_avg REAL, cpu_max REAL, cpu_p95 REAL,
+  mem_min REAL, mem_avg REAL, mem_max REAL, mem_p95 REAL,
+  PRIMARY KEY (host, container_id, bucket_ts)
+);
+INSERT INTO containers_1m_new(host, container_id, image, bucket_ts, samples,
+  cpu_min, cpu_avg, cpu_max, cpu_p95, mem_min, mem_avg, mem_max, mem_p95)
+  SELECT host, container_id, image, bucket_ts, samples,
+  cpu_min, cpu_avg, cpu_max, cpu_p95, mem_min, mem_avg, mem_max, mem_p95 FROM containers_1m;
+DROP TABLE containers_1m;
+ALTER TABLE containers_1m_new RENAME TO containers_1m;
+CREATE INDEX IF NOT EXISTS idx_containers_1m_ts ON containers_1m(bucket_ts);
+
+CREATE TABLE containers_1h_new (
+  host TEXT NOT NULL DEFAULT '',
+  container_id TEXT NOT NULL,
+  image TEXT,
+  bucket_ts INTEGER NOT NULL,
+  samples INTEGER NOT NULL,
+  cpu_min REAL, cpu_avg REAL, cpu_max REAL, cpu_p95 REAL,
+  mem_min REAL, mem_avg REAL, mem_max REAL, mem_p95 REAL,
+  PRIMARY KEY (host, container_id, bucket_ts)
+);
+INSERT INTO containers_1h_new(host, container_id, image, bucket_ts, samples,
+  cpu_min, cpu_avg, cpu_max, cpu_p95, mem_min, mem_avg, mem_max, mem_p95)
+  SELECT host, container_id, image, bucket_ts, samples,
+  cpu_min, cpu_avg, cpu_max, cpu_p95, mem_min, mem_avg, mem_max, mem_p95 FROM containers_1h;
+DROP TABLE containers_1h;
+ALTER TABLE containers_1h_new RENAME TO containers_1h;
+CREATE INDEX IF NOT EXISTS idx_containers_1h_ts ON containers_1h(bucket_ts);
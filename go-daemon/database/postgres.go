@@ -0,0 +1,33 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// OpenPostgres se conecta a PostgreSQL sin modificar su esquema. Varios
+// daemons pueden compartir la base; cada uno registra y consulta sus filas
+// con host.
+func OpenPostgres(dsn, host string) (MetricsStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(4)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("conectar a postgres: %v", err)
+	}
+	return &sqlStore{
+		name:       "postgres",
+		db:         db,
+		host:       host,
+		migrations: filepath.Join(MIGRATIONS_DIR, "postgres"),
+		numbered:   true,
+	}, nil
+}
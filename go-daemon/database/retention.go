@@ -0,0 +1,248 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"so1-daemon/config"
+	"sort"
+	"time"
+)
+
+// ROLLUP_GRACE es el margen antes de cerrar un bucket, para incluir las
+// filas de un ciclo que empezó dentro del bucket y aún no se confirma.
+const ROLLUP_GRACE = 2 * time.Minute
+
+// rollup describe una tabla de agregados generada desde containers.
+// maxBuckets acota los buckets agregados por ejecución: con historial
+// pendiente (primer arranque o tras una pausa larga) se avanza por tramos
+// en cada mantenimiento en lugar de leer toda la tabla de una vez.
+type rollup struct {
+	table      string
+	bucket     time.Duration
+	maxBuckets int64
+	retention  func(config.Retention) time.Duration
+}
+
+// Ambas tablas leen como máximo un día de muestras por ejecución
+var rollups = []rollup{
+	{"containers_1m", time.Minute, 24 * 60, func(r config.Retention) time.Duration { return r.Minute.Duration }},
+	{"containers_1h", time.Hour, 24, func(r config.Retention) time.Duration { return r.Hour.Duration }},
+}
+
+// Tablas sin agregar sujetas a retention.raw
+var rawTables = []string{"containers", "sys_metrics", "process_count", "processes", "cpu_cores"}
+
+// lastVacuum es el momento del último VACUUM (o del arranque).
+var lastVacuum = time.Now()
+
+// Maintain genera los agregados pendientes, borra los datos fuera de la
+// retención y ejecuta VACUUM cuando corresponde. Los datos sin agregar
+// solo se borran una vez incluidos en el agregado de 1 hora.
+func (s *sqlStore) Maintain(r config.Retention, now time.Time) error {
+	var rolledUpTo int64 = math.MaxInt64
+	for _, ru := range rollups {
+		upTo, n, err := s.runRollup(ru, now)
+		if err != nil {
+			return fmt.Errorf("agregar %s: %v", ru.table, err)
+		}
+		if n > 0 {
+			log.Printf("Mantenimiento: %d filas agregadas en %s", n, ru.table)
+		}
+		if upTo < rolledUpTo {
+			rolledUpTo = upTo
+		}
+	}
+
+	if r.Raw.Duration > 0 {
+		cutoff := now.Add(-r.Raw.Duration).Unix()
+		for _, table := range rawTables {
+			limit := cutoff
+			if table == "containers" && rolledUpTo < limit {
+				limit = rolledUpTo
+			}
+			if err := s.deleteBefore(table, "ts", limit); err != nil {
+				return err
+			}
+		}
+	}
+	for _, ru := range rollups {
+		if d := ru.retention(r); d > 0 {
+			if err := s.deleteBefore(ru.table, "bucket_ts", now.Add(-d).Unix()); err != nil {
+				return err
+			}
+		}
+	}
+
+	if r.Vacuum.Duration > 0 && now.Sub(lastVacuum) >= r.Vacuum.Duration {
+		s.lock.Lock()
+		_, err := s.db.Exec("VACUUM")
+		s.lock.Unlock()
+		if err != nil {
+			return fmt.Errorf("vacuum: %v", err)
+		}
+		lastVacuum = now
+		log.Println("Mantenimiento: VACUUM completado")
+	}
+	return nil
+}
+
+func (s *sqlStore) deleteBefore(table, column string, ts int64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s AND %s < ?", table, hostFilter, column)
+	res, err := s.db.Exec(s.q(query), s.host, ts)
+	if err != nil {
+		return fmt.Errorf("borrar %s: %v", table, err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("Mantenimiento: %d filas borradas de %s", n, table)
+	}
+	return nil
+}
+
+type bucketKey struct {
+	containerID string
+	ts          int64
+}
+
+// usageStats son las muestras de un bucket para un contenedor.
+type usageStats struct {
+	image    string
+	cpu, mem []float64
+}
+
+// runRollup agrega en ru.table los buckets completos desde el último
+// agregado hasta now, como máximo ru.maxBuckets. Retorna el fin
+// (exclusivo) del último bucket agregado y la cantidad de filas escritas.
+func (s *sqlStore) runRollup(ru rollup, now time.Time) (int64, int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	size := int64(ru.bucket / time.Second)
+	upTo := now.Add(-ROLLUP_GRACE).Unix() / size * size
+
+	from, err := s.rollupStart(ru.table, size)
+	if err != nil {
+		return 0, 0, err
+	}
+	if from < 0 || from >= upTo {
+		// Sin datos nuevos: todo lo anterior a upTo ya está agregado
+		if from < 0 {
+			return upTo, 0, nil
+		}
+		return from, 0, nil
+	}
+	if limit := from + ru.maxBuckets*size; upTo > limit {
+		upTo = limit
+	}
+
+	rows, err := s.db.Query(
+		s.q("SELECT container_id, image, cpu_pct, mem_pct, ts FROM containers WHERE "+hostFilter+" AND container_id != '' AND ts >= ? AND ts < ?"),
+		s.host, from, upTo)
+	if err != nil {
+		return 0, 0, err
+	}
+	buckets := make(map[bucketKey]*usageStats)
+	for rows.Next() {
+		var id, image string
+		var cpu, mem float64
+		var ts int64
+		if err := rows.Scan(&id, &image, &cpu, &mem, &ts); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		key := bucketKey{id, ts / size * size}
+		st, ok := buckets[key]
+		if !ok {
+			st = &usageStats{image: image}
+			buckets[key] = st
+		}
+		st.cpu = append(st.cpu, cpu)
+		st.mem = append(st.mem, mem)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := s.writeRollup(tx, ru.table, buckets); err != nil {
+		tx.Rollback()
+		return 0, 0, err
+	}
+	if _, err := tx.Exec(s.q(`INSERT INTO rollup_state(name, host, last_ts) VALUES(?,?,?)
+  ON CONFLICT(name, host) DO UPDATE SET last_ts = excluded.last_ts`), ru.table, s.host, upTo); err != nil {
+		tx.Rollback()
+		return 0, 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return upTo, len(buckets), nil
+}
+
+// rollupStart retorna el inicio del primer bucket pendiente de la tabla, o
+// -1 si no hay muestras del host.
+func (s *sqlStore) rollupStart(table string, size int64) (int64, error) {
+	var last int64
+	err := s.db.QueryRow(s.q("SELECT last_ts FROM rollup_state WHERE name = ? AND host = ?"), table, s.host).Scan(&last)
+	if err == nil {
+		return last, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+	var first sql.NullInt64
+	if err := s.db.QueryRow(s.q("SELECT MIN(ts) FROM containers WHERE "+hostFilter), s.host).Scan(&first); err != nil {
+		return 0, err
+	}
+	if !first.Valid {
+		return -1, nil
+	}
+	return first.Int64 / size * size, nil
+}
+
+func (s *sqlStore) writeRollup(tx *sql.Tx, table string, buckets map[bucketKey]*usageStats) error {
+	stmt, err := tx.Prepare(s.q(fmt.Sprintf(`INSERT INTO %s
+  (host, container_id, image, bucket_ts, samples, cpu_min, cpu_avg, cpu_max, cpu_p95, mem_min, mem_avg, mem_max, mem_p95)
+  VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?)
+  ON CONFLICT(host, container_id, bucket_ts) DO UPDATE SET
+    image = excluded.image, samples = excluded.samples,
+    cpu_min = excluded.cpu_min, cpu_avg = excluded.cpu_avg, cpu_max = excluded.cpu_max, cpu_p95 = excluded.cpu_p95,
+    mem_min = excluded.mem_min, mem_avg = excluded.mem_avg, mem_max = excluded.mem_max, mem_p95 = excluded.mem_p95`, table)))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for key, st := range buckets {
+		cMin, cAvg, cMax, cP95 := summarize(st.cpu)
+		mMin, mAvg, mMax, mP95 := summarize(st.mem)
+		if _, err := stmt.Exec(s.host, key.containerID, st.image, key.ts, len(st.cpu), cMin, cAvg, cMax, cP95, mMin, mAvg, mMax, mP95); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// summarize retorna mínimo, promedio, máximo y percentil 95 (rango más
+// cercano) de values. Ordena values.
+func summarize(values []float64) (min, avg, max, p95 float64) {
+	sort.Float64s(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	n := len(values)
+	idx := int(math.Ceil(0.95*float64(n))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return values[0], sum / float64(n), values[n-1], values[idx]
+}
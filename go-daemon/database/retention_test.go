@@ -0,0 +1,84 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openTestSQLite crea una base SQLite temporal con todas las migraciones.
+func openTestSQLite(t *testing.T, host string) *sqlStore {
+	t.Helper()
+	store, err := OpenSQLite(filepath.Join(t.TempDir(), "monitor.db"), host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	s := store.(*sqlStore)
+	if _, err := s.ApplyMigrations(); err != nil {
+		t.Fatalf("migraciones: %v", err)
+	}
+	return s
+}
+
+func insertSample(t *testing.T, s *sqlStore, host, id string, ts int64) {
+	t.Helper()
+	if _, err := s.db.Exec("INSERT INTO containers(host, container_id, image, cpu_pct, mem_pct, ts) VALUES(?,?,?,?,?,?)",
+		host, id, "nginx", 10.0, 20.0, ts); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunRollupCapsBucketsPerRun(t *testing.T) {
+	s := openTestSQLite(t, "a")
+	ru := rollup{table: "containers_1m", bucket: time.Minute, maxBuckets: 3}
+
+	// Una muestra por minuto durante 10 minutos
+	start := int64(1_700_000_040)
+	for i := int64(0); i < 10; i++ {
+		insertSample(t, s, "a", "c1", start+i*60)
+	}
+	now := time.Unix(start+10*60, 0).Add(ROLLUP_GRACE)
+
+	for run, want := range []int64{3, 6, 9, 10, 10} {
+		upTo, _, err := s.runRollup(ru, now)
+		if err != nil {
+			t.Fatalf("ejecución %d: %v", run, err)
+		}
+		if wantTs := start + want*60; upTo != wantTs {
+			t.Errorf("ejecución %d: upTo = %d, se esperaba %d", run, upTo, wantTs)
+		}
+	}
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM containers_1m").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 10 {
+		t.Errorf("containers_1m tiene %d filas, se esperaban 10", n)
+	}
+}
+
+func TestRunRollupKeepsHostsApart(t *testing.T) {
+	a := openTestSQLite(t, "a")
+	// Segundo daemon sobre la misma base con otro host
+	b := &sqlStore{name: "sqlite", db: a.db, host: "b", migrations: a.migrations}
+	ru := rollups[0]
+
+	ts := int64(1_700_000_040)
+	insertSample(t, a, "a", "c1", ts)
+	insertSample(t, a, "b", "c1", ts)
+	now := time.Unix(ts+60, 0).Add(ROLLUP_GRACE)
+
+	for _, s := range []*sqlStore{a, b} {
+		if _, _, err := s.runRollup(ru, now); err != nil {
+			t.Fatalf("host %s: %v", s.host, err)
+		}
+	}
+	var n int
+	if err := a.db.QueryRow("SELECT COUNT(DISTINCT host) FROM containers_1m WHERE container_id = 'c1'").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("containers_1m tiene %d hosts para c1, se esperaban 2", n)
+	}
+}
@@ -0,0 +1,381 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"so1-daemon/var_const"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// sqlStore implementa MetricsStore sobre database/sql. Las consultas se
+// escriben con "?" y se adaptan a los placeholders de cada motor.
+type sqlStore struct {
+	name string
+	db   *sql.DB
+	// Serializa las escrituras (SQLite admite un solo escritor)
+	lock sync.Mutex
+	// Host registrado en cada fila; las consultas y el mantenimiento solo
+	// ven las filas de este host (y las anteriores a la columna host)
+	host string
+	// Directorio de migraciones del motor
+	migrations string
+	// PostgreSQL usa $1, $2...
+	numbered bool
+}
+
+func (s *sqlStore) Name() string { return s.name }
+
+func (s *sqlStore) Close() error { return s.db.Close() }
+
+// q adapta los placeholders de query al motor.
+func (s *sqlStore) q(query string) string {
+	if !s.numbered {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// hostFilter es la condición que limita una consulta a las filas del host.
+const hostFilter = "(host = ? OR host = '')"
+
+func (s *sqlStore) WriteTick(t *Tick) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("iniciar transacción: %v", err)
+	}
+	if err := s.writeTick(tx, t); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("confirmar transacción: %v", err)
+	}
+	return nil
+}
+
+// insertAll prepara query una vez y la ejecuta con cada conjunto de args.
+func (s *sqlStore) insertAll(tx *sql.Tx, table, query string, rows [][]any) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	stmt, err := tx.Prepare(s.q(query))
+	if err != nil {
+		return fmt.Errorf("preparar inserción en %s: %v", table, err)
+	}
+	defer stmt.Close()
+	for _, args := range rows {
+		if _, err := stmt.Exec(args...); err != nil {
+			return fmt.Errorf("insertar en %s: %v", table, err)
+		}
+	}
+	return nil
+}
+
+func (s *sqlStore) writeTick(tx *sql.Tx, t *Tick) error {
+	var rows [][]any
+	for _, r := range t.Sys {
+		row := []any{s.host, int64(r.MemTotalKb), int64(r.MemFreeKb), int64(r.MemUsedKb)}
+		if c := r.Cpu; c != nil {
+			row = append(row, c.BusyPct, c.UserPct, c.SystemPct, c.IdlePct, c.IowaitPct, c.StealPct)
+		} else {
+			row = append(row, nil, nil, nil, nil, nil, nil)
+		}
+		rows = append(rows, append(row, r.Ts))
+	}
+	if err := s.insertAll(tx, "sys_metrics", "INSERT INTO sys_metrics(host, mem_total_kb, mem_free_kb, mem_used_kb, cpu_busy_pct, cpu_user_pct, cpu_system_pct, cpu_idle_pct, cpu_iowait_pct, cpu_steal_pct, ts) VALUES(?,?,?,?,?,?,?,?,?,?,?)", rows); err != nil {
+		return err
+	}
+
+	rows = rows[:0]
+	for _, r := range t.Cores {
+		rows = append(rows, []any{s.host, r.Cpu, r.BusyPct, r.UserPct, r.SystemPct, r.IdlePct, r.IowaitPct, r.StealPct, r.Ts})
+	}
+	if err := s.insertAll(tx, "cpu_cores", "INSERT INTO cpu_cores(host, cpu, busy_pct, user_pct, system_pct, idle_pct, iowait_pct, steal_pct, ts) VALUES(?,?,?,?,?,?,?,?,?)", rows); err != nil {
+		return err
+	}
+
+	rows = rows[:0]
+	for _, r := range t.Processes {
+		rows = append(rows, []any{s.host, r.Total, r.Running, r.Sleeping, r.DiskSleep, r.Stopped, r.Zombie, r.Idle, r.Other, r.Ts})
+	}
+	if err := s.insertAll(tx, "process_count", "INSERT INTO process_count(host, total, running, sleeping, disk_sleep, stopped, zombie, idle, other, ts) VALUES(?,?,?,?,?,?,?,?,?,?)", rows); err != nil {
+		return err
+	}
+
+	rows = rows[:0]
+	for _, r := range t.ProcessSamples {
+		rows = append(rows, []any{s.host, r.Pid, r.Name, r.Cmdline, int64(r.RssKb), int64(r.VszKb), r.MemPct, r.State, r.CpuPct, r.Ts})
+	}
+	if err := s.insertAll(tx, "processes", "INSERT INTO processes(host, pid, name, cmdline, rss_kb, vsz_kb, mem_pct, state, cpu_pct, ts) VALUES(?,?,?,?,?,?,?,?,?,?)", rows); err != nil {
+		return err
+	}
+
+	rows = rows[:0]
+	for _, r := range t.Containers {
+		row := []any{s.host, r.ContainerID, r.Pid, r.Name, r.Image, r.Class, r.State, int64(r.RssKb), int64(r.VszKb), r.Tasks, r.CpuPct, r.CpuHostPct, r.CpuQuotaPct, r.MemPct, r.Decision, r.Reason}
+		row = append(row, cgroupArgs(r.Cgroup)...)
+		rows = append(rows, append(row, r.Ts))
+	}
+	if err := s.insertAll(tx, "containers", "INSERT INTO containers(host, container_id, pid, name, image, class, state, rss_kb, vsz_kb, tasks, cpu_pct, cpu_host_pct, cpu_quota_pct, mem_pct, decision, reason, "+cgroupColumns+", ts) VALUES("+placeholders(17+cgroupColumnCount)+")", rows); err != nil {
+		return err
+	}
+
+	rows = rows[:0]
+	for _, r := range t.Deletions {
+		rows = append(rows, []any{s.host, r.ContainerID, r.Reason, r.Ts})
+	}
+	if err := s.insertAll(tx, "deletions", "INSERT INTO deletions(host, container_id, reason, ts) VALUES(?,?,?,?)", rows); err != nil {
+		return err
+	}
+
+	rows = rows[:0]
+	for _, r := range t.Actions {
+		rows = append(rows, []any{s.host, r.ContainerID, r.Image, r.Class, r.Action, r.Reason, r.CpuPct, r.MemPct, r.DryRun, r.Ts})
+	}
+	return s.insertAll(tx, "actions", "INSERT INTO actions(host, container_id, image, class, action, reason, cpu_pct, mem_pct, dry_run, ts) VALUES(?,?,?,?,?,?,?,?,?,?)", rows)
+}
+
+// query ejecuta "SELECT columns FROM table WHERE <host> [AND extra] <rango>"
+// ordenado del más reciente al más antiguo y llama a scan por cada fila.
+func (s *sqlStore) query(table, columns, extra string, extraArgs []any, r Range, scan func(*sql.Rows) error) error {
+	where := []string{hostFilter}
+	args := []any{s.host}
+	if extra != "" {
+		where = append(where, extra)
+		args = append(args, extraArgs...)
+	}
+	if r.From > 0 {
+		where = append(where, "ts >= ?")
+		args = append(args, r.From)
+	}
+	if r.To > 0 {
+		where = append(where, "ts <= ?")
+		args = append(args, r.To)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY ts DESC, id DESC", columns, table, strings.Join(where, " AND "))
+	if r.Limit > 0 {
+		query += " LIMIT " + strconv.Itoa(r.Limit)
+	}
+
+	rows, err := s.db.Query(s.q(query), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (s *sqlStore) QuerySysMetrics(r Range) ([]var_const.SysSample, error) {
+	result := []var_const.SysSample{}
+	columns := "mem_total_kb, mem_free_kb, mem_used_kb, cpu_busy_pct, cpu_user_pct, cpu_system_pct, cpu_idle_pct, cpu_iowait_pct, cpu_steal_pct, ts"
+	err := s.query("sys_metrics", columns, "", nil, r, func(rows *sql.Rows) error {
+		var v var_const.SysSample
+		var cpu [6]sql.NullFloat64
+		if err := rows.Scan(&v.MemTotalKb, &v.MemFreeKb, &v.MemUsedKb, &cpu[0], &cpu[1], &cpu[2], &cpu[3], &cpu[4], &cpu[5], &v.Ts); err != nil {
+			return err
+		}
+		// Las filas del primer ciclo y las anteriores a la migración no tienen CPU
+		if cpu[0].Valid {
+			v.Cpu = &var_const.CpuUsage{
+				BusyPct: cpu[0].Float64, UserPct: cpu[1].Float64, SystemPct: cpu[2].Float64,
+				IdlePct: cpu[3].Float64, IowaitPct: cpu[4].Float64, StealPct: cpu[5].Float64,
+			}
+		}
+		result = append(result, v)
+		return nil
+	})
+	return result, err
+}
+
+func (s *sqlStore) QueryCpuCores(r Range) ([]var_const.CoreSample, error) {
+	result := []var_const.CoreSample{}
+	err := s.query("cpu_cores", "cpu, busy_pct, user_pct, system_pct, idle_pct, iowait_pct, steal_pct, ts", "", nil, r, func(rows *sql.Rows) error {
+		var v var_const.CoreSample
+		if err := rows.Scan(&v.Cpu, &v.BusyPct, &v.UserPct, &v.SystemPct, &v.IdlePct, &v.IowaitPct, &v.StealPct, &v.Ts); err != nil {
+			return err
+		}
+		result = append(result, v)
+		return nil
+	})
+	return result, err
+}
+
+// QueryContainers retorna las muestras de un contenedor, o de todos si
+// containerID está vacío.
+func (s *sqlStore) QueryContainers(containerID string, r Range) ([]var_const.ContainerSample, error) {
+	extra, extraArgs := "", []any(nil)
+	if containerID != "" {
+		extra, extraArgs = "container_id = ?", []any{containerID}
+	}
+	result := []var_const.ContainerSample{}
+	columns := "container_id, pid, COALESCE(name, ''), image, COALESCE(class, ''), COALESCE(state, ''), COALESCE(rss_kb, 0), COALESCE(vsz_kb, 0), COALESCE(tasks, 0), cpu_pct, COALESCE(cpu_host_pct, 0), COALESCE(cpu_quota_pct, 0), mem_pct, COALESCE(decision, ''), COALESCE(reason, ''), " + cgroupColumns + ", ts"
+	err := s.query("containers", columns, extra, extraArgs, r, func(rows *sql.Rows) error {
+		var v var_const.ContainerSample
+		var cg cgroupScan
+		dest := []any{&v.ContainerID, &v.Pid, &v.Name, &v.Image, &v.Class, &v.State, &v.RssKb, &v.VszKb, &v.Tasks, &v.CpuPct, &v.CpuHostPct, &v.CpuQuotaPct, &v.MemPct, &v.Decision, &v.Reason}
+		dest = append(dest, cg.dest()...)
+		if err := rows.Scan(append(dest, &v.Ts)...); err != nil {
+			return err
+		}
+		v.Cgroup = cg.stats()
+		result = append(result, v)
+		return nil
+	})
+	return result, err
+}
+
+func (s *sqlStore) QueryProcessCount(r Range) ([]var_const.ProcessCountSample, error) {
+	result := []var_const.ProcessCountSample{}
+	err := s.query("process_count", "total, running, sleeping, disk_sleep, stopped, zombie, idle, other, ts", "", nil, r, func(rows *sql.Rows) error {
+		var v var_const.ProcessCountSample
+		if err := rows.Scan(&v.Total, &v.Running, &v.Sleeping, &v.DiskSleep, &v.Stopped, &v.Zombie, &v.Idle, &v.Other, &v.Ts); err != nil {
+			return err
+		}
+		result = append(result, v)
+		return nil
+	})
+	return result, err
+}
+
+func (s *sqlStore) QueryProcesses(pid int, r Range) ([]var_const.ProcessSample, error) {
+	extra, extraArgs := "", []any(nil)
+	if pid != 0 {
+		extra, extraArgs = "pid = ?", []any{pid}
+	}
+	result := []var_const.ProcessSample{}
+	err := s.query("processes", "pid, name, cmdline, rss_kb, vsz_kb, mem_pct, state, cpu_pct, ts", extra, extraArgs, r, func(rows *sql.Rows) error {
+		var v var_const.ProcessSample
+		if err := rows.Scan(&v.Pid, &v.Name, &v.Cmdline, &v.RssKb, &v.VszKb, &v.MemPct, &v.State, &v.CpuPct, &v.Ts); err != nil {
+			return err
+		}
+		result = append(result, v)
+		return nil
+	})
+	return result, err
+}
+
+func (s *sqlStore) QueryDeletions(r Range) ([]var_const.DeletionRecord, error) {
+	result := []var_const.DeletionRecord{}
+	err := s.query("deletions", "container_id, reason, ts", "", nil, r, func(rows *sql.Rows) error {
+		var v var_const.DeletionRecord
+		if err := rows.Scan(&v.ContainerID, &v.Reason, &v.Ts); err != nil {
+			return err
+		}
+		result = append(result, v)
+		return nil
+	})
+	return result, err
+}
+
+func (s *sqlStore) QueryActions(r Range) ([]var_const.ActionRecord, error) {
+	result := []var_const.ActionRecord{}
+	err := s.query("actions", "container_id, image, class, action, reason, cpu_pct, mem_pct, dry_run, ts", "", nil, r, func(rows *sql.Rows) error {
+		var v var_const.ActionRecord
+		if err := rows.Scan(&v.ContainerID, &v.Image, &v.Class, &v.Action, &v.Reason, &v.CpuPct, &v.MemPct, &v.DryRun, &v.Ts); err != nil {
+			return err
+		}
+		result = append(result, v)
+		return nil
+	})
+	return result, err
+}
+
+// placeholders retorna "?,?,...,?" con n marcadores.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// cgroupColumns son las columnas de containers con los recursos del cgroup.
+const cgroupColumns = "cgroup_version, mem_current_bytes, mem_working_set_bytes, mem_max_bytes, mem_anon_bytes, mem_file_bytes, " +
+	"io_read_bytes, io_write_bytes, io_read_ops, io_write_ops, pids_current, " +
+	"cpu_psi_some_avg10, mem_psi_some_avg10, mem_psi_full_avg10, io_psi_some_avg10, io_psi_full_avg10, cpu_limit"
+
+const cgroupColumnCount = 17
+
+// cgroupArgs retorna los valores de cgroupColumns; todos NULL si c es nil.
+func cgroupArgs(c *var_const.CgroupStats) []any {
+	if c == nil {
+		return make([]any, cgroupColumnCount)
+	}
+	psi := func(p *var_const.Pressure, full bool) any {
+		switch {
+		case p == nil:
+			return nil
+		case full:
+			return p.Full.Avg10
+		default:
+			return p.Some.Avg10
+		}
+	}
+	return []any{
+		c.Version, int64(c.MemoryCurrent), int64(c.MemoryWorkingSet), int64(c.MemoryMax), int64(c.MemoryAnon), int64(c.MemoryFile),
+		int64(c.Io.ReadBytes), int64(c.Io.WriteBytes), int64(c.Io.ReadOps), int64(c.Io.WriteOps), int64(c.PidsCurrent),
+		psi(c.CpuPressure, false), psi(c.MemoryPressure, false), psi(c.MemoryPressure, true), psi(c.IoPressure, false), psi(c.IoPressure, true),
+		c.CpuLimit,
+	}
+}
+
+// cgroupScan recibe las columnas cgroupColumns de una fila.
+type cgroupScan struct {
+	version                                   sql.NullInt64
+	values                                    [10]sql.NullInt64
+	cpuSome, memSome, memFull, ioSome, ioFull sql.NullFloat64
+	cpuLimit                                  sql.NullFloat64
+}
+
+func (c *cgroupScan) dest() []any {
+	dest := []any{&c.version}
+	for i := range c.values {
+		dest = append(dest, &c.values[i])
+	}
+	return append(dest, &c.cpuSome, &c.memSome, &c.memFull, &c.ioSome, &c.ioFull, &c.cpuLimit)
+}
+
+// stats reconstruye los recursos leídos; nil si la fila no tiene cgroup.
+func (c *cgroupScan) stats() *var_const.CgroupStats {
+	if !c.version.Valid {
+		return nil
+	}
+	v := func(i int) uint64 { return uint64(c.values[i].Int64) }
+	st := &var_const.CgroupStats{
+		Version:          int(c.version.Int64),
+		MemoryCurrent:    v(0),
+		MemoryWorkingSet: v(1),
+		MemoryMax:        v(2),
+		MemoryAnon:       v(3),
+		MemoryFile:       v(4),
+		Io:               var_const.CgroupIo{ReadBytes: v(5), WriteBytes: v(6), ReadOps: v(7), WriteOps: v(8)},
+		PidsCurrent:      v(9),
+		CpuLimit:         c.cpuLimit.Float64,
+	}
+	pressure := func(some, full sql.NullFloat64) *var_const.Pressure {
+		if !some.Valid {
+			return nil
+		}
+		return &var_const.Pressure{Some: var_const.PressureLine{Avg10: some.Float64}, Full: var_const.PressureLine{Avg10: full.Float64}}
+	}
+	st.CpuPressure = pressure(c.cpuSome, sql.NullFloat64{})
+	st.MemoryPressure = pressure(c.memSome, c.memFull)
+	st.IoPressure = pressure(c.ioSome, c.ioFull)
+	return st
+}
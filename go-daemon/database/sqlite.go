@@ -0,0 +1,61 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLITE_PRAGMAS se aplican a cada conexión. WAL permite que Grafana lea
+// mientras el daemon escribe; busy_timeout espera en lugar de fallar con
+// SQLITE_BUSY cuando otro proceso tiene el lock de escritura.
+var SQLITE_PRAGMAS = []string{
+	"journal_mode(WAL)",
+	"busy_timeout(5000)",
+	"synchronous(NORMAL)",
+	"foreign_keys(ON)",
+}
+
+// OpenSQLite abre (o crea) la base SQLite en dbPath sin modificar su
+// esquema. host se registra en cada fila escrita.
+func OpenSQLite(dbPath, host string) (MetricsStore, error) {
+	dataDir := filepath.Dir(dbPath)
+	if _, err := os.Stat(dataDir); os.IsNotExist(err) {
+		if err := os.MkdirAll(dataDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	db, err := sql.Open("sqlite", dsn(dbPath))
+	if err != nil {
+		return nil, err
+	}
+	// Verifica que el archivo se pueda abrir y que WAL quedó activo
+	var mode string
+	if err := db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("abrir %s: %v", dbPath, err)
+	}
+	if mode != "wal" {
+		log.Printf("Advertencia: la base %s usa journal_mode=%s en lugar de wal", dbPath, mode)
+	}
+	return &sqlStore{
+		name:       "sqlite",
+		db:         db,
+		host:       host,
+		migrations: filepath.Join(MIGRATIONS_DIR, "sqlite"),
+	}, nil
+}
+
+// dsn agrega los pragmas de SQLITE_PRAGMAS a la ruta de la base.
+func dsn(dbPath string) string {
+	q := url.Values{}
+	for _, p := range SQLITE_PRAGMAS {
+		q.Add("_pragma", p)
+	}
+	return "file:" + dbPath + "?" + q.Encode()
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"so1-daemon/config"
+	"so1-daemon/var_const"
+	"time"
+)
+
+// Range acota una consulta por marca de tiempo (Unix, segundos). From y To
+// en 0 no limitan; Limit en 0 retorna todas las filas. Los resultados se
+// retornan del más reciente al más antiguo.
+type Range struct {
+	From  int64
+	To    int64
+	Limit int
+}
+
+// MetricsStore es el almacenamiento de las métricas del daemon. Permite
+// usar SQLite (un host), PostgreSQL (varios hosts en una base) o memoria
+// (pruebas y ejecuciones sin disco).
+type MetricsStore interface {
+	// Name retorna el nombre del backend ("sqlite", "postgres", "memory").
+	Name() string
+	// WriteTick escribe todas las filas de un ciclo de forma atómica.
+	WriteTick(t *Tick) error
+
+	QuerySysMetrics(r Range) ([]var_const.SysSample, error)
+	QueryCpuCores(r Range) ([]var_const.CoreSample, error)
+	QueryContainers(containerID string, r Range) ([]var_const.ContainerSample, error)
+	QueryProcessCount(r Range) ([]var_const.ProcessCountSample, error)
+	// QueryProcesses retorna las instantáneas de un PID, o de todos si pid es 0.
+	QueryProcesses(pid int, r Range) ([]var_const.ProcessSample, error)
+	QueryDeletions(r Range) ([]var_const.DeletionRecord, error)
+	QueryActions(r Range) ([]var_const.ActionRecord, error)
+
+	// Maintain genera los agregados y aplica la retención configurada.
+	Maintain(r config.Retention, now time.Time) error
+	Close() error
+}
+
+// Migrator lo implementan los backends con esquema versionado.
+type Migrator interface {
+	MigrationsStatus() ([]MigrationStatus, error)
+	ApplyMigrations() ([]Migration, error)
+}
+
+// Store es el almacenamiento activo, creado por InitDB u OpenStore.
+var Store MetricsStore = NewMemoryStore()
+
+// OpenStore crea el almacenamiento seleccionado en la configuración sin
+// modificar su esquema y lo deja activo en Store.
+func OpenStore(cfg *config.Config) error {
+	var (
+		s   MetricsStore
+		err error
+	)
+	switch cfg.Storage {
+	case "sqlite":
+		s, err = OpenSQLite(cfg.DBPath, cfg.HostName)
+	case "postgres":
+		s, err = OpenPostgres(cfg.PostgresDSN, cfg.HostName)
+	case "memory":
+		s = NewMemoryStore()
+	default:
+		err = fmt.Errorf("almacenamiento desconocido: %q", cfg.Storage)
+	}
+	if err != nil {
+		return err
+	}
+	Store = s
+	return nil
+}
+
+// InitDB abre el almacenamiento configurado y aplica las migraciones
+// pendientes.
+func InitDB() error {
+	if err := OpenStore(config.Get()); err != nil {
+		return err
+	}
+	m, ok := Store.(Migrator)
+	if !ok {
+		return nil
+	}
+	applied, err := m.ApplyMigrations()
+	for _, mig := range applied {
+		log.Printf("Migración aplicada: %04d_%s", mig.Version, mig.Name)
+	}
+	return err
+}
+
+// CloseDB cierra el almacenamiento activo.
+func CloseDB() error {
+	return Store.Close()
+}
+
+// StartMaintenance ejecuta Store.Maintain cada retention.interval hasta
+// que stop se cierre. La configuración se vuelve a leer en cada ejecución,
+// por lo que los cambios por SIGHUP se aplican sin reiniciar.
+func StartMaintenance(stop <-chan struct{}) {
+	go func() {
+		timer := time.NewTimer(config.Get().Retention.Interval.Duration)
+		defer timer.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-timer.C:
+				if err := Store.Maintain(config.Get().Retention, time.Now()); err != nil {
+					log.Printf("Advertencia: mantenimiento de la base de datos: %v", err)
+				}
+				timer.Reset(config.Get().Retention.Interval.Duration)
+			}
+		}
+	}()
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"so1-daemon/var_const"
+	"time"
+)
+
+// Tick acumula las filas de un ciclo de ProcessOnce para escribirlas en
+// una sola transacción con Commit. Todas las filas comparten la marca de
+// tiempo del inicio del ciclo. No es seguro para uso concurrente.
+type Tick struct {
+	Ts             int64
+	Sys            []var_const.SysSample
+	Cores          []var_const.CoreSample
+	Processes      []var_const.ProcessCountSample
+	ProcessSamples []var_const.ProcessSample
+	Containers     []var_const.ContainerSample
+	Deletions      []var_const.DeletionRecord
+	Actions        []var_const.ActionRecord
+}
+
+// NewTick crea un lote vacío con la marca de tiempo now.
+func NewTick(now time.Time) *Tick {
+	return &Tick{Ts: now.Unix()}
+}
+
+// AddSysMetrics registra la memoria del sistema y el uso de CPU del host
+// (nil si aún no hay lectura previa de /proc/stat).
+func (t *Tick) AddSysMetrics(total, free, used uint64, cpu *var_const.CpuUsage) {
+	t.Sys = append(t.Sys, var_const.SysSample{MemTotalKb: total, MemFreeKb: free, MemUsedKb: used, Cpu: cpu, Ts: t.Ts})
+}
+
+// AddCpuCores registra el uso de cada núcleo.
+func (t *Tick) AddCpuCores(cores []var_const.CoreUsage) {
+	for _, c := range cores {
+		t.Cores = append(t.Cores, var_const.CoreSample{CoreUsage: c, Ts: t.Ts})
+	}
+}
+
+// AddProcessCount registra el total de procesos y su conteo por estado.
+func (t *Tick) AddProcessCount(total int, states var_const.ProcessStates) {
+	t.Processes = append(t.Processes, var_const.ProcessCountSample{Total: total, ProcessStates: states, Ts: t.Ts})
+}
+
+// AddProcessSample agrega la instantánea de un proceso del host.
+func (t *Tick) AddProcessSample(p var_const.ProcessSample) {
+	p.Ts = t.Ts
+	t.ProcessSamples = append(t.ProcessSamples, p)
+}
+
+// AddContainerRecord agrega la muestra de un contenedor; Ts se toma del lote.
+func (t *Tick) AddContainerRecord(c var_const.ContainerSample) {
+	c.Ts = t.Ts
+	t.Containers = append(t.Containers, c)
+}
+
+func (t *Tick) AddDeletion(containerID, reason string) {
+	t.Deletions = append(t.Deletions, var_const.DeletionRecord{ContainerID: containerID, Reason: reason, Ts: t.Ts})
+}
+
+// AddAction registra una decisión tomada sobre un contenedor. Con
+// dryRun=true la acción solo se simuló (modo observación).
+func (t *Tick) AddAction(containerID, image, class, action, reason string, cpuPct, memPct float64, dryRun bool) {
+	t.Actions = append(t.Actions, var_const.ActionRecord{
+		ContainerID: containerID, Image: image, Class: class, Action: action, Reason: reason,
+		CpuPct: cpuPct, MemPct: memPct, DryRun: dryRun, Ts: t.Ts,
+	})
+}
+
+// Rows retorna la cantidad de filas pendientes de escribir.
+func (t *Tick) Rows() int {
+	return len(t.Sys) + len(t.Cores) + len(t.Processes) + len(t.ProcessSamples) + len(t.Containers) + len(t.Deletions) + len(t.Actions)
+}
+
+// Commit escribe las filas del ciclo en Store. Si alguna inserción falla
+// no se escribe ninguna fila del ciclo y se retorna el error. El lote
+// queda vacío en ambos casos.
+func (t *Tick) Commit() error {
+	defer t.reset()
+	if t.Rows() == 0 {
+		return nil
+	}
+	return Store.WriteTick(t)
+}
+
+func (t *Tick) reset() {
+	t.Sys, t.Cores, t.Processes, t.ProcessSamples, t.Containers, t.Deletions, t.Actions = nil, nil, nil, nil, nil, nil, nil
+}
@@ -0,0 +1,227 @@
+package dockerapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DEFAULT_SOCKET es el socket unix donde escucha el Docker Engine.
+const DEFAULT_SOCKET = "/var/run/docker.sock"
+
+// REQUEST_TIMEOUT limita cada petición al Engine. Stop espera además el
+// periodo de gracia del contenedor (ver STOP_MARGIN).
+const REQUEST_TIMEOUT = 10 * time.Second
+
+// STOP_MARGIN se suma a timeoutSec en Stop: el Engine responde recién
+// cuando el contenedor terminó, a lo sumo timeoutSec después de SIGTERM.
+const STOP_MARGIN = 10 * time.Second
+
+// ErrNotFound se retorna cuando el Engine responde 404 (contenedor inexistente).
+var ErrNotFound = errors.New("contenedor no encontrado")
+
+// Container es el resumen que retorna GET /containers/json.
+type Container struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Image  string            `json:"Image"`
+	State  string            `json:"State"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// ContainerJSON contiene los campos de GET /containers/{id}/json que usa el daemon.
+type ContainerJSON struct {
+	ID    string `json:"Id"`
+	Name  string `json:"Name"`
+	State struct {
+		Status  string `json:"Status"`
+		Running bool   `json:"Running"`
+		Paused  bool   `json:"Paused"`
+		Pid     int    `json:"Pid"`
+	} `json:"State"`
+	Config struct {
+		Image  string            `json:"Image"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+}
+
+// Stats es una muestra única de GET /containers/{id}/stats?stream=false.
+type Stats struct {
+	Read     time.Time `json:"read"`
+	CPUStats struct {
+		CPUUsage struct {
+			TotalUsage uint64 `json:"total_usage"` // nanosegundos
+		} `json:"cpu_usage"`
+		SystemUsage uint64 `json:"system_cpu_usage"`
+		OnlineCPUs  int    `json:"online_cpus"`
+	} `json:"cpu_stats"`
+	MemoryStats struct {
+		Usage uint64 `json:"usage"`
+		Limit uint64 `json:"limit"`
+	} `json:"memory_stats"`
+}
+
+// Client define las operaciones del Engine API que necesita el daemon.
+// Permite sustituir la implementación real por una falsa (por ejemplo un
+// servidor HTTP escuchando en otro socket unix).
+type Client interface {
+	// List retorna los contenedores en ejecución.
+	List() ([]Container, error)
+	// Inspect retorna el detalle de un contenedor.
+	Inspect(id string) (ContainerJSON, error)
+	// Remove elimina un contenedor (equivalente a docker rm [-f]).
+	Remove(id string, force bool) error
+	// Stats retorna una muestra de uso de CPU y memoria.
+	Stats(id string) (Stats, error)
+	// Update cambia los límites de recursos (equivalente a docker update).
+	Update(id string, res Resources) error
+	// Pause congela los procesos del contenedor.
+	Pause(id string) error
+	// Stop detiene el contenedor esperando timeoutSec antes de SIGKILL.
+	Stop(id string, timeoutSec int) error
+}
+
+// Resources son los límites aceptados por POST /containers/{id}/update.
+// Los valores en 0 no se modifican.
+type Resources struct {
+	NanoCpus   int64 `json:"NanoCpus,omitempty"`
+	Memory     int64 `json:"Memory,omitempty"`
+	MemorySwap int64 `json:"MemorySwap,omitempty"`
+}
+
+// socketClient implementa Client hablando HTTP sobre un socket unix.
+type socketClient struct {
+	http *http.Client
+}
+
+// NewClient crea un cliente para el Engine API expuesto en socketPath.
+func NewClient(socketPath string) Client {
+	if socketPath == "" {
+		socketPath = DEFAULT_SOCKET
+	}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		},
+		MaxIdleConns:    2,
+		IdleConnTimeout: 90 * time.Second,
+	}
+	return &socketClient{
+		// Sin Timeout global: cada petición define su plazo con un contexto
+		http: &http.Client{Transport: transport},
+	}
+}
+
+// do ejecuta la petición con el plazo REQUEST_TIMEOUT (ver doTimeout).
+func (c *socketClient) do(method, path string, query url.Values, in, out any) error {
+	return c.doTimeout(REQUEST_TIMEOUT, method, path, query, in, out)
+}
+
+// doTimeout ejecuta la petición (con cuerpo JSON si in no es nil) y
+// decodifica la respuesta JSON en out (si no es nil). La petición se
+// cancela si no termina en timeout.
+func (c *socketClient) doTimeout(timeout time.Duration, method, path string, query url.Values, in, out any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	u := "http://docker" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("docker %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("docker %s %s: %w", method, path, ErrNotFound)
+	}
+	if resp.StatusCode >= 300 {
+		// El Engine retorna {"message": "..."} en los errores
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if json.Unmarshal(b, &apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(b))
+		}
+		return fmt.Errorf("docker %s %s: %s: %s", method, path, resp.Status, apiErr.Message)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("docker %s %s: respuesta inválida: %w", method, path, err)
+	}
+	return nil
+}
+
+func (c *socketClient) List() ([]Container, error) {
+	var list []Container
+	if err := c.do(http.MethodGet, "/containers/json", nil, nil, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (c *socketClient) Inspect(id string) (ContainerJSON, error) {
+	var info ContainerJSON
+	err := c.do(http.MethodGet, "/containers/"+url.PathEscape(id)+"/json", nil, nil, &info)
+	return info, err
+}
+
+func (c *socketClient) Remove(id string, force bool) error {
+	q := url.Values{}
+	if force {
+		q.Set("force", "true")
+	}
+	return c.do(http.MethodDelete, "/containers/"+url.PathEscape(id), q, nil, nil)
+}
+
+func (c *socketClient) Stats(id string) (Stats, error) {
+	var s Stats
+	q := url.Values{"stream": {"false"}, "one-shot": {"true"}}
+	err := c.do(http.MethodGet, "/containers/"+url.PathEscape(id)+"/stats", q, nil, &s)
+	return s, err
+}
+
+func (c *socketClient) Update(id string, res Resources) error {
+	return c.do(http.MethodPost, "/containers/"+url.PathEscape(id)+"/update", nil, res, nil)
+}
+
+func (c *socketClient) Pause(id string) error {
+	return c.do(http.MethodPost, "/containers/"+url.PathEscape(id)+"/pause", nil, nil, nil)
+}
+
+func (c *socketClient) Stop(id string, timeoutSec int) error {
+	q := url.Values{"t": {strconv.Itoa(timeoutSec)}}
+	timeout := time.Duration(max(timeoutSec, 0))*time.Second + STOP_MARGIN
+	return c.doTimeout(timeout, http.MethodPost, "/containers/"+url.PathEscape(id)+"/stop", q, nil, nil)
+}
@@ -0,0 +1,138 @@
+package dockerapi
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestClient levanta handler en un socket unix temporal y retorna un
+// cliente conectado a él.
+func newTestClient(t *testing.T, handler http.Handler) Client {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skip("no se pudo crear el socket unix:", err)
+	}
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener.Close()
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return NewClient(socket)
+}
+
+func TestList(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/containers/json" {
+			t.Errorf("petición inesperada %s %s", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`[{"Id":"abc","Names":["/web"],"Image":"nginx","State":"running","Labels":{"k":"v"}}]`))
+	}))
+
+	list, err := c.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != "abc" || list[0].Names[0] != "/web" || list[0].Labels["k"] != "v" {
+		t.Fatalf("List = %+v", list)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/abc/json":
+			w.Write([]byte(`{"Id":"abc","Name":"/web","State":{"Status":"running","Running":true,"Pid":4321},"Config":{"Image":"nginx"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"No such container"}`))
+		}
+	}))
+
+	info, err := c.Inspect("abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.State.Pid != 4321 || info.Config.Image != "nginx" || !info.State.Running {
+		t.Fatalf("Inspect = %+v", info)
+	}
+
+	if _, err := c.Inspect("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Inspect de un contenedor inexistente: %v, se esperaba ErrNotFound", err)
+	}
+}
+
+func TestStop(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/containers/abc/stop" {
+			t.Errorf("petición inesperada %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.URL.Query().Get("t"); got != "7" {
+			t.Errorf("t = %q, se esperaba 7", got)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	if err := c.Stop("abc", 7); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	var force string
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Path != "/containers/abc" {
+			t.Errorf("petición inesperada %s %s", r.Method, r.URL.Path)
+		}
+		force = r.URL.Query().Get("force")
+		if force == "" {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"message":"container is running"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	if err := c.Remove("abc", true); err != nil || force != "true" {
+		t.Fatalf("Remove forzado: err=%v force=%q", err, force)
+	}
+	err := c.Remove("abc", false)
+	if err == nil {
+		t.Fatal("Remove sin force debía fallar con 409")
+	}
+	if want := "container is running"; !strings.Contains(err.Error(), want) {
+		t.Fatalf("error %q no incluye el mensaje del Engine %q", err, want)
+	}
+}
+
+func TestStats(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/containers/abc/stats" {
+			t.Errorf("petición inesperada %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.URL.Query().Get("stream"); got != "false" {
+			t.Errorf("stream = %q, se esperaba false", got)
+		}
+		w.Write([]byte(`{"read":"2026-01-02T03:04:05Z","cpu_stats":{"cpu_usage":{"total_usage":123456789},"system_cpu_usage":987654321,"online_cpus":4},"memory_stats":{"usage":1048576,"limit":2097152}}`))
+	}))
+
+	st, err := c.Stats("abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.CPUStats.CPUUsage.TotalUsage != 123456789 || st.CPUStats.SystemUsage != 987654321 || st.CPUStats.OnlineCPUs != 4 {
+		t.Fatalf("cpu_stats = %+v", st.CPUStats)
+	}
+	if st.MemoryStats.Usage != 1048576 || st.MemoryStats.Limit != 2097152 {
+		t.Fatalf("memory_stats = %+v", st.MemoryStats)
+	}
+	if st.Read.IsZero() {
+		t.Fatal("read vacío")
+	}
+}
@@ -0,0 +1,379 @@
+package functions
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"so1-daemon/var_const"
+	"strconv"
+	"strings"
+)
+
+// CGROUP_ROOT es el punto de montaje de las jerarquías de cgroups. En v2
+// es la jerarquía unificada; en v1 contiene un directorio por controlador
+// (memory, blkio, pids...).
+var CGROUP_ROOT = "/sys/fs/cgroup"
+
+// V1_UNLIMITED es el umbral a partir del cual memory.limit_in_bytes de
+// cgroups v1 se considera sin límite (el kernel publica un valor cercano
+// a 2^63 redondeado a página).
+const V1_UNLIMITED = uint64(1) << 62
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// ReadCgroupStats lee la memoria, E/S, PIDs y presión (PSI) del cgroup de
+// un contenedor (ver ResolveCgroup). Se usa v2 si la jerarquía unificada
+// tiene el controlador de memoria; en modo híbrido (unified sin
+// controladores) se leen los de v1. Solo falla si no se puede leer el uso
+// de memoria; los demás archivos dependen de los controladores habilitados
+// y se omiten si no existen. PSI solo existe en cgroups v2.
+func ReadCgroupStats(paths CgroupPaths) (var_const.CgroupStats, error) {
+	if paths.V2 != "" && fileExists(filepath.Join(paths.V2, "memory.current")) {
+		return readCgroupV2(paths.V2)
+	}
+	if paths.V1["memory"] == "" {
+		return var_const.CgroupStats{}, fmt.Errorf("el cgroup no tiene el controlador de memoria")
+	}
+	return readCgroupV1(paths.V1)
+}
+
+func readCgroupV2(dir string) (var_const.CgroupStats, error) {
+	st := var_const.CgroupStats{Version: 2}
+
+	current, err := readCgroupUint(filepath.Join(dir, "memory.current"))
+	if err != nil {
+		return st, err
+	}
+	st.MemoryCurrent = current
+	st.MemoryMax, _ = readCgroupUint(filepath.Join(dir, "memory.max"))
+
+	if stat, err := readKeyValues(filepath.Join(dir, "memory.stat")); err == nil {
+		st.MemoryAnon = stat["anon"]
+		st.MemoryFile = stat["file"]
+		st.MemoryWorkingSet = workingSet(current, stat["inactive_file"])
+	} else {
+		st.MemoryWorkingSet = current
+	}
+
+	if io, err := readIoStatV2(filepath.Join(dir, "io.stat")); err == nil {
+		st.Io = io
+	}
+	st.PidsCurrent, _ = readCgroupUint(filepath.Join(dir, "pids.current"))
+	st.CpuLimit, _ = readCpuMax(filepath.Join(dir, "cpu.max"))
+
+	st.CpuPressure, _ = readPressure(filepath.Join(dir, "cpu.pressure"))
+	st.MemoryPressure, _ = readPressure(filepath.Join(dir, "memory.pressure"))
+	st.IoPressure, _ = readPressure(filepath.Join(dir, "io.pressure"))
+	return st, nil
+}
+
+func readCgroupV1(dirs map[string]string) (var_const.CgroupStats, error) {
+	st := var_const.CgroupStats{Version: 1}
+	mem := dirs["memory"]
+
+	current, err := readCgroupUint(filepath.Join(mem, "memory.usage_in_bytes"))
+	if err != nil {
+		return st, err
+	}
+	st.MemoryCurrent = current
+	if limit, err := readCgroupUint(filepath.Join(mem, "memory.limit_in_bytes")); err == nil && limit < V1_UNLIMITED {
+		st.MemoryMax = limit
+	}
+
+	// total_* incluye los cgroups hijos, igual que los valores de v2
+	if stat, err := readKeyValues(filepath.Join(mem, "memory.stat")); err == nil {
+		st.MemoryAnon = stat["total_rss"]
+		st.MemoryFile = stat["total_cache"]
+		st.MemoryWorkingSet = workingSet(current, stat["total_inactive_file"])
+	} else {
+		st.MemoryWorkingSet = current
+	}
+
+	// Los controladores no montados no tienen directorio; una ruta vacía
+	// se resolvería relativa al directorio de trabajo del daemon
+	if blkio := dirs["blkio"]; blkio != "" {
+		if bytes, err := readBlkioV1(filepath.Join(blkio, "blkio.throttle.io_service_bytes")); err == nil {
+			st.Io.ReadBytes, st.Io.WriteBytes = bytes[0], bytes[1]
+		}
+		if ops, err := readBlkioV1(filepath.Join(blkio, "blkio.throttle.io_serviced")); err == nil {
+			st.Io.ReadOps, st.Io.WriteOps = ops[0], ops[1]
+		}
+	}
+	if pids := dirs["pids"]; pids != "" {
+		st.PidsCurrent, _ = readCgroupUint(filepath.Join(pids, "pids.current"))
+	}
+	if cpu := dirs["cpu"]; cpu != "" {
+		st.CpuLimit, _ = readCfsQuota(cpu)
+	}
+	return st, nil
+}
+
+// CFS_PERIOD_US es el periodo de CPU (microsegundos) con el que se
+// escriben las cuotas, el mismo que usa docker update --cpus.
+const CFS_PERIOD_US = 100000
+
+// WriteCgroupLimits escribe los límites de CPUs y memoria (bytes) en el
+// cgroup del contenedor, para los runtimes sin API de actualización (ver
+// cruntime.ErrNoUpdateAPI). Cada controlador se escribe en v2 (cpu.max,
+// memory.max) si la jerarquía unificada lo tiene; si no, en su directorio
+// de v1 (cpu.cfs_quota_us, memory.limit_in_bytes). Los valores en 0 no se
+// modifican.
+func WriteCgroupLimits(paths CgroupPaths, cpus float64, memoryBytes int64) error {
+	quota := int64(cpus * CFS_PERIOD_US)
+	if cpus > 0 {
+		switch {
+		case paths.V2 != "" && fileExists(filepath.Join(paths.V2, "cpu.max")):
+			// cpu.max: "<quota> <periodo>" en microsegundos
+			if err := writeCgroupFile(paths.V2, "cpu.max", fmt.Sprintf("%d %d", quota, CFS_PERIOD_US)); err != nil {
+				return err
+			}
+		case paths.V1["cpu"] != "":
+			if err := writeCgroupFile(paths.V1["cpu"], "cpu.cfs_period_us", strconv.Itoa(CFS_PERIOD_US)); err != nil {
+				return err
+			}
+			if err := writeCgroupFile(paths.V1["cpu"], "cpu.cfs_quota_us", strconv.FormatInt(quota, 10)); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("el cgroup no tiene el controlador de CPU")
+		}
+	}
+	if memoryBytes > 0 {
+		value := strconv.FormatInt(memoryBytes, 10)
+		switch {
+		case paths.V2 != "" && fileExists(filepath.Join(paths.V2, "memory.max")):
+			if err := writeCgroupFile(paths.V2, "memory.max", value); err != nil {
+				return err
+			}
+		case paths.V1["memory"] != "":
+			if err := writeCgroupFile(paths.V1["memory"], "memory.limit_in_bytes", value); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("el cgroup no tiene el controlador de memoria")
+		}
+	}
+	return nil
+}
+
+func writeCgroupFile(dir, name, value string) error {
+	return os.WriteFile(filepath.Join(dir, name), []byte(value+"\n"), 0644)
+}
+
+// workingSet descuenta la caché inactiva del uso, igual que docker stats.
+func workingSet(current, inactiveFile uint64) uint64 {
+	if inactiveFile > current {
+		return 0
+	}
+	return current - inactiveFile
+}
+
+// readCgroupUint lee un archivo con un único entero. "max" (sin límite en
+// v2) se retorna como 0.
+func readCgroupUint(path string) (uint64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	s := strings.TrimSpace(string(b))
+	if s == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// readCpuMax lee la cuota de cgroups v2 ("<quota> <periodo>" en
+// microsegundos, "max 100000" sin cuota) y la retorna en CPUs.
+func readCpuMax(path string) (float64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("%s: formato inesperado %q", path, strings.TrimSpace(string(b)))
+	}
+	if fields[0] == "max" {
+		return 0, nil
+	}
+	return cpuQuota(fields[0], fields[1])
+}
+
+// readCfsQuota lee la cuota de cgroups v1 (cpu.cfs_quota_us, -1 sin
+// cuota, y cpu.cfs_period_us) y la retorna en CPUs.
+func readCfsQuota(dir string) (float64, error) {
+	quota, err := os.ReadFile(filepath.Join(dir, "cpu.cfs_quota_us"))
+	if err != nil {
+		return 0, err
+	}
+	period, err := os.ReadFile(filepath.Join(dir, "cpu.cfs_period_us"))
+	if err != nil {
+		return 0, err
+	}
+	q := strings.TrimSpace(string(quota))
+	if strings.HasPrefix(q, "-") {
+		return 0, nil
+	}
+	return cpuQuota(q, strings.TrimSpace(string(period)))
+}
+
+func cpuQuota(quota, period string) (float64, error) {
+	q, err := strconv.ParseUint(quota, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	p, err := strconv.ParseUint(period, 10, 64)
+	if err != nil || p == 0 {
+		return 0, fmt.Errorf("periodo inválido %q", period)
+	}
+	return float64(q) / float64(p), nil
+}
+
+// readKeyValues lee archivos con líneas "clave valor" como memory.stat.
+func readKeyValues(path string) (map[string]uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = v
+		}
+	}
+	return values, scanner.Err()
+}
+
+// readIoStatV2 suma los contadores de todos los dispositivos de io.stat:
+//
+//	8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0
+func readIoStatV2(path string) (var_const.CgroupIo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return var_const.CgroupIo{}, err
+	}
+	defer f.Close()
+
+	var io var_const.CgroupIo
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		for _, kv := range fields[min(1, len(fields)):] {
+			key, value, ok := strings.Cut(kv, "=")
+			if !ok {
+				continue
+			}
+			v, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch key {
+			case "rbytes":
+				io.ReadBytes += v
+			case "wbytes":
+				io.WriteBytes += v
+			case "rios":
+				io.ReadOps += v
+			case "wios":
+				io.WriteOps += v
+			}
+		}
+	}
+	return io, scanner.Err()
+}
+
+// readBlkioV1 suma las líneas Read y Write de los archivos blkio de v1:
+//
+//	8:0 Read 1459200
+//	8:0 Write 314773504
+//	Total 316232704
+func readBlkioV1(path string) ([2]uint64, error) {
+	var rw [2]uint64
+	f, err := os.Open(path)
+	if err != nil {
+		return rw, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[1] {
+		case "Read":
+			rw[0] += v
+		case "Write":
+			rw[1] += v
+		}
+	}
+	return rw, scanner.Err()
+}
+
+// readPressure lee un archivo PSI (*.pressure):
+//
+//	some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//
+// cpu.pressure no tiene la línea full en kernels anteriores a 5.13.
+func readPressure(path string) (*var_const.Pressure, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	p := &var_const.Pressure{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var line *var_const.PressureLine
+		switch fields[0] {
+		case "some":
+			line = &p.Some
+		case "full":
+			line = &p.Full
+		default:
+			continue
+		}
+		for _, kv := range fields[1:] {
+			key, value, ok := strings.Cut(kv, "=")
+			if !ok {
+				continue
+			}
+			switch key {
+			case "avg10":
+				line.Avg10, _ = strconv.ParseFloat(value, 64)
+			case "avg60":
+				line.Avg60, _ = strconv.ParseFloat(value, 64)
+			case "avg300":
+				line.Avg300, _ = strconv.ParseFloat(value, 64)
+			case "total":
+				line.TotalUsec, _ = strconv.ParseUint(value, 10, 64)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
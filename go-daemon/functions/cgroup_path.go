@@ -0,0 +1,293 @@
+package functions
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PROC_MOUNTINFO lista los montajes visibles para el daemon; de aquí se
+// obtienen los puntos de montaje de cada jerarquía de cgroups.
+const PROC_MOUNTINFO = "/proc/self/mountinfo"
+
+// V1_CONTROLLERS son los controladores de cgroups v1 que lee el daemon.
+var V1_CONTROLLERS = []string{"cpu", "cpuacct", "memory", "blkio", "pids"}
+
+// CgroupPaths son los directorios absolutos del cgroup de un contenedor.
+// V2 es su directorio en la jerarquía unificada (vacío si no la hay); en
+// v1 e híbrido cada controlador tiene además su propio directorio en V1.
+type CgroupPaths struct {
+	V2 string
+	V1 map[string]string // controlador -> directorio
+}
+
+// exists indica si el directorio del cgroup sigue existiendo. Cuando el
+// contenedor se reinicia con el mismo ID su cgroup se recrea, por lo que
+// basta con comprobar que no se haya eliminado.
+func (p CgroupPaths) exists() bool {
+	if p.V2 != "" {
+		return fileExists(p.V2)
+	}
+	for _, dir := range p.V1 {
+		if fileExists(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// cgroupMount es un montaje de cgroups de /proc/self/mountinfo. Root es la
+// ruta del cgroup que se ve en Mountpoint (distinta de "/" dentro de otro
+// contenedor o con cgroup namespaces).
+type cgroupMount struct {
+	Root        string
+	Mountpoint  string
+	V2          bool
+	Controllers []string // solo v1
+}
+
+// procCgroupEntry es una línea "id-jerarquía:controladores:ruta" de
+// /proc/<pid>/cgroup. En v2 la línea es "0::<ruta>".
+type procCgroupEntry struct {
+	Hierarchy   string
+	Controllers []string
+	Path        string
+}
+
+// Directorios resueltos por contenedor
+var (
+	cgroupCache     = make(map[string]CgroupPaths)
+	cgroupCacheLock sync.Mutex
+)
+
+// ResolveCgroup retorna los directorios del cgroup del contenedor id. La
+// ruta se resuelve a partir de /proc/<pid>/cgroup del PID principal y los
+// montajes de /proc/self/mountinfo, lo que funciona con cualquier driver
+// (systemd o cgroupfs), Docker rootless y contenedores anidados. Si no se
+// puede resolver se prueban los directorios de dirs (ver
+// ContainerRuntime.CgroupDirs). El resultado se guarda en caché mientras el
+// directorio exista.
+func ResolveCgroup(id string, pid int, dirs []string) (CgroupPaths, error) {
+	cgroupCacheLock.Lock()
+	defer cgroupCacheLock.Unlock()
+
+	if p, ok := cgroupCache[id]; ok && p.exists() {
+		return p, nil
+	}
+
+	p, err := cgroupFromProc(pid)
+	if err != nil {
+		var guessErr error
+		if p, guessErr = guessCgroup(dirs); guessErr != nil {
+			return CgroupPaths{}, fmt.Errorf("%v; %v", err, guessErr)
+		}
+	}
+	cgroupCache[id] = p
+	return p, nil
+}
+
+// pruneCgroupCache descarta los contenedores que no están en alive.
+func pruneCgroupCache(alive map[string]bool) {
+	cgroupCacheLock.Lock()
+	defer cgroupCacheLock.Unlock()
+	for id := range cgroupCache {
+		if !alive[id] {
+			delete(cgroupCache, id)
+		}
+	}
+}
+
+// cgroupFromProc une cada línea de /proc/<pid>/cgroup con el montaje de
+// su jerarquía. En modo híbrido se obtienen tanto V2 (unified) como los
+// controladores de V1.
+func cgroupFromProc(pid int) (CgroupPaths, error) {
+	entries, err := readProcCgroup(pid)
+	if err != nil {
+		return CgroupPaths{}, err
+	}
+	mounts, err := readCgroupMounts(PROC_MOUNTINFO)
+	if err != nil {
+		return CgroupPaths{}, err
+	}
+
+	p := CgroupPaths{V1: make(map[string]string)}
+	for _, e := range entries {
+		if e.Hierarchy == "0" && len(e.Controllers) == 0 {
+			if m, ok := findMount(mounts, true, ""); ok {
+				p.V2 = m.join(e.Path)
+			}
+			continue
+		}
+		for _, ctrl := range e.Controllers {
+			if !slices.Contains(V1_CONTROLLERS, ctrl) {
+				continue
+			}
+			if m, ok := findMount(mounts, false, ctrl); ok {
+				p.V1[ctrl] = m.join(e.Path)
+			}
+		}
+	}
+
+	if p.V2 != "" && !fileExists(p.V2) {
+		p.V2 = ""
+	}
+	for ctrl, dir := range p.V1 {
+		if !fileExists(dir) {
+			delete(p.V1, ctrl)
+		}
+	}
+	if p.V2 == "" && len(p.V1) == 0 {
+		return CgroupPaths{}, fmt.Errorf("el cgroup del PID %d no está montado en este namespace", pid)
+	}
+	return p, nil
+}
+
+// join traduce la ruta de /proc/<pid>/cgroup a un directorio bajo el punto
+// de montaje. Si el montaje no es la raíz de la jerarquía se descuenta su
+// prefijo.
+func (m cgroupMount) join(path string) string {
+	if m.Root != "/" {
+		if rel, ok := strings.CutPrefix(path, m.Root); ok && (rel == "" || rel[0] == '/') {
+			path = rel
+		}
+	}
+	return filepath.Join(m.Mountpoint, path)
+}
+
+// findMount busca el montaje v2 o el montaje v1 que contiene ctrl. Si un
+// controlador está montado varias veces se usa el primero.
+func findMount(mounts []cgroupMount, v2 bool, ctrl string) (cgroupMount, bool) {
+	for _, m := range mounts {
+		if m.V2 != v2 {
+			continue
+		}
+		if v2 || slices.Contains(m.Controllers, ctrl) {
+			return m, true
+		}
+	}
+	return cgroupMount{}, false
+}
+
+// readProcCgroup lee /proc/<pid>/cgroup:
+//
+//	12:memory:/docker/0123abcd
+//	4:cpu,cpuacct:/docker/0123abcd
+//	1:name=systemd:/docker/0123abcd
+//	0::/system.slice/docker-0123abcd.scope
+func readProcCgroup(pid int) ([]procCgroupEntry, error) {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []procCgroupEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		e := procCgroupEntry{Hierarchy: parts[0], Path: parts[2]}
+		if parts[1] != "" {
+			e.Controllers = strings.Split(parts[1], ",")
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// readCgroupMounts extrae los montajes cgroup y cgroup2 de mountinfo:
+//
+//	33 32 0:29 / /sys/fs/cgroup/cpu,cpuacct rw,relatime shared:9 - cgroup cgroup rw,cpu,cpuacct
+//	34 24 0:30 / /sys/fs/cgroup/unified rw,relatime shared:10 - cgroup2 cgroup2 rw
+//
+// Los campos opcionales (shared:N...) terminan en "-"; después vienen el
+// tipo de sistema de archivos, el origen y las opciones del superbloque,
+// que en v1 incluyen los controladores.
+func readCgroupMounts(path string) ([]cgroupMount, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mounts []cgroupMount
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 0 || sep+1 >= len(fields) {
+			continue
+		}
+
+		m := cgroupMount{Root: unescapeMountPath(fields[3]), Mountpoint: unescapeMountPath(fields[4])}
+		switch fields[sep+1] {
+		case "cgroup2":
+			m.V2 = true
+		case "cgroup":
+			if sep+3 < len(fields) {
+				m.Controllers = strings.Split(fields[sep+3], ",")
+			}
+		default:
+			continue
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMountPath decodifica los caracteres que mountinfo escapa en
+// octal (espacio \040, tabulador \011, salto de línea \012 y \134).
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// guessCgroup prueba cada directorio de dirs (relativos a la raíz) en la
+// jerarquía v2, en la unificada del modo híbrido y en los controladores de
+// v1. Solo se usa cuando no se puede leer /proc/<pid>/cgroup.
+func guessCgroup(dirs []string) (CgroupPaths, error) {
+	for _, dir := range dirs {
+		p := CgroupPaths{V1: make(map[string]string)}
+		for _, v2 := range []string{filepath.Join(CGROUP_ROOT, dir), filepath.Join(CGROUP_ROOT, "unified", dir)} {
+			if fileExists(filepath.Join(v2, "cgroup.controllers")) {
+				p.V2 = v2
+				break
+			}
+		}
+		for _, ctrl := range V1_CONTROLLERS {
+			if d := filepath.Join(CGROUP_ROOT, ctrl, dir); fileExists(d) {
+				p.V1[ctrl] = d
+			}
+		}
+		if p.V2 != "" || len(p.V1) > 0 {
+			return p, nil
+		}
+	}
+	return CgroupPaths{}, fmt.Errorf("cgroup no encontrado en %s para %v", CGROUP_ROOT, dirs)
+}
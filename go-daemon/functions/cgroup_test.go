@@ -0,0 +1,96 @@
+package functions
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeCgroupDir crea un directorio de cgroup con los archivos indicados
+// (vacíos) y lo retorna.
+func fakeCgroupDir(t *testing.T, files ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, f), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func readTrimmed(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func TestWriteCgroupLimits(t *testing.T) {
+	v2 := fakeCgroupDir(t, "cpu.max", "memory.max")
+	cpuV1 := fakeCgroupDir(t, "cpu.cfs_quota_us", "cpu.cfs_period_us")
+	memV1 := fakeCgroupDir(t, "memory.limit_in_bytes")
+	// Modo híbrido: la jerarquía unificada no tiene controladores
+	unified := fakeCgroupDir(t)
+
+	tests := []struct {
+		name  string
+		paths CgroupPaths
+		want  map[string]string // archivo -> contenido
+	}{
+		{
+			name:  "v2",
+			paths: CgroupPaths{V2: v2},
+			want: map[string]string{
+				filepath.Join(v2, "cpu.max"):    "150000 100000",
+				filepath.Join(v2, "memory.max"): "268435456",
+			},
+		},
+		{
+			name:  "v1",
+			paths: CgroupPaths{V1: map[string]string{"cpu": cpuV1, "memory": memV1}},
+			want: map[string]string{
+				filepath.Join(cpuV1, "cpu.cfs_period_us"):     "100000",
+				filepath.Join(cpuV1, "cpu.cfs_quota_us"):      "150000",
+				filepath.Join(memV1, "memory.limit_in_bytes"): "268435456",
+			},
+		},
+		{
+			name:  "híbrido",
+			paths: CgroupPaths{V2: unified, V1: map[string]string{"cpu": cpuV1, "memory": memV1}},
+			want: map[string]string{
+				filepath.Join(cpuV1, "cpu.cfs_quota_us"):      "150000",
+				filepath.Join(memV1, "memory.limit_in_bytes"): "268435456",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := WriteCgroupLimits(tt.paths, 1.5, 256*1024*1024); err != nil {
+				t.Fatal(err)
+			}
+			for path, want := range tt.want {
+				if got := readTrimmed(t, path); got != want {
+					t.Errorf("%s = %q, se esperaba %q", filepath.Base(path), got, want)
+				}
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(unified, "cpu.max")); err == nil {
+		t.Error("se escribió cpu.max en la jerarquía unificada sin controladores")
+	}
+}
+
+func TestWriteCgroupLimitsMissingController(t *testing.T) {
+	paths := CgroupPaths{V1: map[string]string{"cpu": fakeCgroupDir(t, "cpu.cfs_quota_us")}}
+	if err := WriteCgroupLimits(paths, 0, 1024); err == nil {
+		t.Error("se esperaba un error sin el controlador de memoria")
+	}
+	// Los valores en 0 no se escriben
+	if err := WriteCgroupLimits(paths, 0, 0); err != nil {
+		t.Errorf("sin límites: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package functions
+
+import (
+	"fmt"
+	"so1-daemon/config"
+	"so1-daemon/rules"
+	"so1-daemon/var_const"
+	"time"
+)
+
+// RecordUsage agrega el uso medido en el ciclo now al historial de la
+// serie key en PrevSamples y actualiza sus promedios móviles. La memoria
+// se registra siempre; si el %CPU no es válido (cpuOk false: primera
+// lectura, contador reiniciado o lectura fallida) la muestra se marca sin
+// CPU y el promedio de CPU no cambia. Si la serie ya se registró en este
+// ciclo el historial no cambia. Retorna el estado actualizado.
+func RecordUsage(key var_const.SampleKey, cpu var_const.CpuViews, cpuOk bool, memPct float64, now time.Time, det config.Detection) var_const.PidCpuSample {
+	var_const.PrevSamplesLock.Lock()
+	defer var_const.PrevSamplesLock.Unlock()
+
+	s := var_const.PrevSamples[key]
+	if s.RecordedAt.Equal(now) {
+		return s
+	}
+	s.RecordedAt = now
+
+	ewma := func(v, prev float64) float64 { return det.Alpha*v + (1-det.Alpha)*prev }
+	if len(s.History) == 0 {
+		s.EwmaMem = memPct
+	} else {
+		s.EwmaMem = ewma(memPct, s.EwmaMem)
+	}
+	if !cpuOk {
+		cpu = var_const.CpuViews{}
+	} else if !s.EwmaCpuOk {
+		s.EwmaCpu, s.EwmaCpuOk = cpu, true
+	} else {
+		s.EwmaCpu = var_const.CpuViews{
+			Raw:   ewma(cpu.Raw, s.EwmaCpu.Raw),
+			Host:  ewma(cpu.Host, s.EwmaCpu.Host),
+			Quota: ewma(cpu.Quota, s.EwmaCpu.Quota),
+		}
+	}
+
+	size := det.Window
+	if size < 1 {
+		size = 1
+	}
+	s.History = append(s.History, var_const.UsageSample{Cpu: cpu, CpuOk: cpuOk, Mem: memPct})
+	if len(s.History) > size {
+		s.History = append([]var_const.UsageSample(nil), s.History[len(s.History)-size:]...)
+	}
+
+	var_const.PrevSamples[key] = s
+	return s
+}
+
+// hasCpu indica si el historial tiene alguna muestra con %CPU válido.
+func hasCpu(history []var_const.UsageSample) bool {
+	for _, u := range history {
+		if u.CpuOk {
+			return true
+		}
+	}
+	return false
+}
+
+// SustainedViolation decide si la clase está en violación considerando el
+// historial de uso y no solo la muestra actual:
+//
+//   - instant: la muestra más reciente supera el umbral
+//   - window:  al menos Required de las últimas Window muestras lo superan
+//   - ewma:    el promedio móvil exponencial supera el umbral
+//
+// Las muestras sin %CPU válido solo se evalúan por memoria (su Cpu es 0),
+// igual que el promedio de CPU mientras no haya ninguna lectura. Sin
+// historial nunca hay violación.
+func SustainedViolation(cfg *config.Config, cls *rules.Class, s var_const.PidCpuSample) (bool, string) {
+	if len(s.History) == 0 {
+		return false, ""
+	}
+	det := cfg.Detection
+	last := s.History[len(s.History)-1]
+
+	switch det.Mode {
+	case config.DETECT_EWMA:
+		ok, reason := cls.Violation(s.EwmaCpu, s.EwmaMem, cfg.CPUThreshold, cfg.MemThreshold, cfg.CPUView)
+		if !ok {
+			return false, ""
+		}
+		return true, reason + " (ewma)"
+
+	case config.DETECT_WINDOW:
+		hits := 0
+		for _, u := range s.History {
+			if ok, _ := cls.Violation(u.Cpu, u.Mem, cfg.CPUThreshold, cfg.MemThreshold, cfg.CPUView); ok {
+				hits++
+			}
+		}
+		if hits < det.Required {
+			return false, ""
+		}
+		// La razón describe la muestra más reciente que superó el umbral
+		reason := ""
+		for i := len(s.History) - 1; i >= 0 && reason == ""; i-- {
+			_, reason = cls.Violation(s.History[i].Cpu, s.History[i].Mem, cfg.CPUThreshold, cfg.MemThreshold, cfg.CPUView)
+		}
+		return true, fmt.Sprintf("%s en %d de %d ciclos", reason, hits, len(s.History))
+
+	default:
+		return cls.Violation(last.Cpu, last.Mem, cfg.CPUThreshold, cfg.MemThreshold, cfg.CPUView)
+	}
+}
@@ -0,0 +1,80 @@
+package functions
+
+import (
+	"so1-daemon/config"
+	"so1-daemon/rules"
+	"so1-daemon/var_const"
+	"testing"
+	"time"
+)
+
+func TestRecordUsageKeepsMemoryWithoutCpu(t *testing.T) {
+	key := var_const.SampleKey{ContainerID: "detect-test", Unit: var_const.CPU_UNIT_NS}
+	t.Cleanup(func() {
+		var_const.PrevSamplesLock.Lock()
+		delete(var_const.PrevSamples, key)
+		var_const.PrevSamplesLock.Unlock()
+	})
+
+	det := config.Detection{Mode: config.DETECT_EWMA, Window: 3, Alpha: 0.5}
+	t0 := time.Unix(1_700_000_000, 0)
+	cpu := var_const.CpuViews{Raw: 80, Host: 20, Quota: 80}
+
+	// Primer ciclo: aún no hay %CPU, la memoria se registra igual
+	s := RecordUsage(key, cpu, false, 40, t0, det)
+	if len(s.History) != 1 || s.History[0].CpuOk || s.History[0].Mem != 40 || s.History[0].Cpu.Raw != 0 {
+		t.Fatalf("primer ciclo: historial %+v", s.History)
+	}
+	if s.EwmaCpuOk || s.EwmaMem != 40 {
+		t.Errorf("primer ciclo: EwmaCpuOk = %v, EwmaMem = %.2f", s.EwmaCpuOk, s.EwmaMem)
+	}
+	if hasCpu(s.History) {
+		t.Error("hasCpu sin ninguna muestra de CPU válida")
+	}
+
+	// La misma serie dos veces en un ciclo se registra una sola vez
+	if s = RecordUsage(key, cpu, true, 60, t0, det); len(s.History) != 1 {
+		t.Fatalf("segunda llamada en el mismo ciclo: %d muestras", len(s.History))
+	}
+
+	t1 := t0.Add(20 * time.Second)
+	s = RecordUsage(key, cpu, true, 60, t1, det)
+	if !s.EwmaCpuOk || s.EwmaCpu != cpu || s.EwmaMem != 50 {
+		t.Errorf("primer %%CPU válido: EwmaCpu = %+v, EwmaMem = %.2f", s.EwmaCpu, s.EwmaMem)
+	}
+
+	// Una lectura fallida conserva el promedio de CPU
+	t2 := t1.Add(20 * time.Second)
+	s = RecordUsage(key, var_const.CpuViews{}, false, 70, t2, det)
+	if s.EwmaCpu != cpu || s.EwmaMem != 60 {
+		t.Errorf("lectura fallida: EwmaCpu = %+v, EwmaMem = %.2f", s.EwmaCpu, s.EwmaMem)
+	}
+	if len(s.History) != 3 || s.History[2].CpuOk || !hasCpu(s.History) {
+		t.Errorf("lectura fallida: historial %+v", s.History)
+	}
+}
+
+func TestSustainedViolationWithoutCpu(t *testing.T) {
+	// Muestras sin %CPU válido: solo la memoria puede estar en violación
+	history := []var_const.UsageSample{{Mem: 95}, {Mem: 95}}
+	memClass := &rules.Class{Name: "mem", Evaluate: []string{rules.METRIC_MEM}}
+	cpuClass := &rules.Class{Name: "cpu", Evaluate: []string{rules.METRIC_CPU}}
+
+	for _, mode := range []string{config.DETECT_INSTANT, config.DETECT_WINDOW, config.DETECT_EWMA} {
+		cfg := &config.Config{
+			CPUThreshold: 50,
+			MemThreshold: 90,
+			CPUView:      rules.CPU_VIEW_RAW,
+			Detection:    config.Detection{Mode: mode, Window: 2, Required: 2, Alpha: 0.5},
+		}
+		s := var_const.PidCpuSample{History: history, EwmaMem: 95}
+		if ok, reason := SustainedViolation(cfg, memClass, s); !ok {
+			t.Errorf("%s: la memoria sin %%CPU no se evaluó", mode)
+		} else if rules.ReasonMetrics(reason) != rules.METRIC_MEM {
+			t.Errorf("%s: razón %q", mode, reason)
+		}
+		if ok, reason := SustainedViolation(cfg, cpuClass, s); ok {
+			t.Errorf("%s: violación de CPU sin %%CPU válido: %q", mode, reason)
+		}
+	}
+}
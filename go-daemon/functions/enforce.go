@@ -0,0 +1,304 @@
+package functions
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"so1-daemon/config"
+	"so1-daemon/cruntime"
+	"so1-daemon/database"
+	"so1-daemon/metrics"
+	"so1-daemon/rules"
+	"so1-daemon/var_const"
+	"sync"
+	"time"
+)
+
+// Decisiones registradas con cada muestra (columna decision de containers)
+// cuando no se aplica ninguna acción; si se aplica, la decisión es la
+// acción (throttle, pause, stop, remove).
+const (
+	DECISION_NONE          = "none"          // sin violación sostenida
+	DECISION_WARMUP        = "warmup"        // primera muestra de la serie, aún sin %CPU
+	DECISION_PROTECTED     = "protected"     // clase protegida
+	DECISION_UNCLASSIFIED  = "unclassified"  // no coincide con ninguna clase
+	DECISION_NOT_CONTAINER = "not-container" // en violación pero sin ID de contenedor
+	DECISION_COOLDOWN      = "cooldown"      // enfriamiento tras la última acción
+	DECISION_PENDING       = "pending"       // en violación, sin alcanzar el siguiente escalón
+	DECISION_MIN_COUNT     = "min-count"     // la acción dejaría al grupo bajo su mínimo
+	DECISION_FAILED        = "failed"        // el runtime rechazó la acción
+)
+
+// enforceState guarda el avance de un contenedor en la escalera de
+// sanciones de su clase.
+type enforceState struct {
+	Streak  int    // ciclos consecutivos en violación
+	Applied int    // índice del último escalón aplicado (-1 ninguno)
+	Action  string // última acción aplicada
+	// Action solo se simuló (dry-run); ver sanctioned
+	DryRun bool
+	Class  string
+	Image  string
+	Reason string
+	Seen   bool // el contenedor apareció en el ciclo actual
+
+	// Hasta este momento el contenedor no se reevalúa tras una acción
+	CooldownUntil time.Time
+}
+
+var (
+	enforcement     = make(map[string]*enforceState)
+	enforcementLock sync.Mutex
+)
+
+// sanctioned indica si la última acción sacó al contenedor de servicio
+// (pause o stop). En dry-run una sanción simulada cuenta igual que una
+// real, para que la escalera y los mínimos de grupo avancen como lo harían
+// en modo real; si el dry-run se desactiva, las sanciones simuladas dejan
+// de contar.
+func (st *enforceState) sanctioned(dryRun bool) bool {
+	if st.Action != rules.ACTION_PAUSE && st.Action != rules.ACTION_STOP {
+		return false
+	}
+	return !st.DryRun || dryRun
+}
+
+// isSanctioned indica si el contenedor ya fue pausado o detenido (ver
+// sanctioned), en cuyo caso no cuenta como activo para los mínimos de su
+// grupo.
+func isSanctioned(containerID string, dryRun bool) bool {
+	enforcementLock.Lock()
+	defer enforcementLock.Unlock()
+	st, ok := enforcement[containerID]
+	return ok && st.sanctioned(dryRun)
+}
+
+// beginEnforcementTick marca todos los estados como no vistos.
+func beginEnforcementTick() {
+	enforcementLock.Lock()
+	defer enforcementLock.Unlock()
+	for _, st := range enforcement {
+		st.Seen = false
+	}
+}
+
+// nextStep actualiza la racha del contenedor y retorna el escalón que
+// corresponde aplicar en este ciclo, si lo hay. Durante el periodo de
+// enfriamiento posterior a una acción el contenedor no se reevalúa.
+//
+// Un contenedor pausado sigue contando como en violación (su CPU cae a 0
+// por estar congelado); en dry-run también uno detenido en simulación, que
+// en modo real dejaría de aparecer y avanzaría en finishEnforcementTick.
+// Un contenedor que deja de violar tras un throttle conserva el límite
+// pero reinicia su racha.
+func nextStep(containerID string, cls *rules.Class, image string, violating bool, reason string, dryRun bool, now time.Time) (int, rules.Step, *enforceState, bool) {
+	enforcementLock.Lock()
+	defer enforcementLock.Unlock()
+
+	st, ok := enforcement[containerID]
+	if ok {
+		st.Seen = true
+		if now.Before(st.CooldownUntil) {
+			return 0, rules.Step{}, st, false
+		}
+	}
+	if ok && st.sanctioned(dryRun) && (st.Action == rules.ACTION_PAUSE || st.DryRun) {
+		violating = true
+		if reason == "" {
+			reason = st.Reason
+		}
+	}
+
+	if !violating {
+		if ok {
+			if st.Applied < 0 {
+				delete(enforcement, containerID)
+			} else {
+				st.Streak = 0
+			}
+		}
+		return 0, rules.Step{}, nil, false
+	}
+
+	if !ok {
+		st = &enforceState{Applied: -1, Seen: true}
+		enforcement[containerID] = st
+	}
+	st.Streak++
+	st.Class = cls.Name
+	st.Image = image
+	st.Reason = reason
+
+	return dueStep(cls.Ladder(), st)
+}
+
+// pendingDecision describe por qué un contenedor con estado en la escalera
+// no recibió una acción en este ciclo.
+func pendingDecision(cls *rules.Class, st *enforceState, now time.Time) (string, string) {
+	enforcementLock.Lock()
+	defer enforcementLock.Unlock()
+
+	if now.Before(st.CooldownUntil) {
+		return DECISION_COOLDOWN, fmt.Sprintf("%s aplicado; enfriamiento hasta %s", st.Action, st.CooldownUntil.Format("15:04:05"))
+	}
+	ladder := cls.Ladder()
+	if next := st.Applied + 1; next < len(ladder) {
+		return DECISION_PENDING, fmt.Sprintf("%s; racha %d de %d para %s", st.Reason, st.Streak, ladder[next].After, ladder[next].Action)
+	}
+	return DECISION_PENDING, st.Reason
+}
+
+// dueStep busca el escalón más severo cuyo umbral de ciclos ya se cumplió
+// y que aún no se haya aplicado.
+func dueStep(ladder []rules.Step, st *enforceState) (int, rules.Step, *enforceState, bool) {
+	for i := len(ladder) - 1; i > st.Applied; i-- {
+		if st.Streak >= ladder[i].After {
+			return i, ladder[i], st, true
+		}
+	}
+	return 0, rules.Step{}, st, false
+}
+
+// enforceContainer avanza la escalera de un contenedor y aplica el escalón
+// que corresponda. Las acciones que sacan al contenedor de servicio
+// respetan el mínimo del grupo y lo descuentan de groupCount, también en
+// dry-run. Retorna la decisión y su razón; ok es false si el contenedor
+// no tiene estado en la escalera (sin violación).
+func enforceContainer(cfg *config.Config, tick *database.Tick, groupCount map[string]int, cls *rules.Class, info var_const.DockerInfo, violating bool, reason string, cpuPct, memPct float64, now time.Time) (decision, why string, ok bool) {
+	idx, step, state, due := nextStep(info.ContainerID, cls, info.Image, violating, reason, cfg.DryRun, now)
+	if !due {
+		if state == nil {
+			return "", "", false
+		}
+		decision, why = pendingDecision(cls, state, now)
+		return decision, why, true
+	}
+
+	// El throttle no saca al contenedor de servicio; el resto de
+	// acciones respeta el mínimo del grupo
+	group := cls.GroupName()
+	wasActive := !state.sanctioned(cfg.DryRun)
+	if step.Action != rules.ACTION_THROTTLE && wasActive && groupCount[group] <= cls.MinCount {
+		log.Printf("Se aplicaría %s a %s, pero se infringiría el mínimo del grupo %s (%d)", step.Action, info.ContainerID, group, cls.MinCount)
+		return DECISION_MIN_COUNT, fmt.Sprintf("%s; %s omitido: el grupo %s tiene %d contenedores (mínimo %d)", state.Reason, step.Action, group, groupCount[group], cls.MinCount), true
+	}
+
+	if !applyStep(tick, cfg.DryRun, cfg.Detection.Cooldown.Duration, info.ContainerID, idx, step, state, cpuPct, memPct) {
+		return DECISION_FAILED, fmt.Sprintf("no se pudo aplicar %s: %s", step.Action, state.Reason), true
+	}
+	if step.Action != rules.ACTION_THROTTLE && wasActive {
+		groupCount[group]--
+	}
+	why = state.Reason
+	if cfg.DryRun {
+		why = "[dry-run] " + why
+	}
+	return step.Action, why, true
+}
+
+// throttle aplica el límite con la API del runtime. Si el runtime no la
+// tiene (containerd) el límite se escribe en el cgroup resuelto del
+// contenedor, el mismo del que se leen sus estadísticas.
+func throttle(containerID string, cpus float64, memoryBytes int64) error {
+	err := Runtime.Throttle(containerID, cpus, memoryBytes)
+	if !errors.Is(err, cruntime.ErrNoUpdateAPI) {
+		return err
+	}
+	info, err := Runtime.InfoByID(containerID)
+	if err != nil {
+		return err
+	}
+	paths, err := ResolveCgroup(info.ContainerID, info.Pid, Runtime.CgroupDirs(info))
+	if err != nil {
+		return err
+	}
+	return WriteCgroupLimits(paths, cpus, memoryBytes)
+}
+
+// applyStep ejecuta (o simula en dry-run) la acción sobre el contenedor y
+// la agrega al lote del ciclo (tabla actions). Retorna true si la acción
+// se aplicó.
+func applyStep(tick *database.Tick, dryRun bool, cooldown time.Duration, containerID string, idx int, step rules.Step, st *enforceState, cpuPct, memPct float64) bool {
+	reason := fmt.Sprintf("%s (%d ciclos consecutivos)", st.Reason, st.Streak)
+
+	if dryRun {
+		log.Printf("[dry-run] Se aplicaría %s al contenedor %s debido a %s (cpu=%.2f mem=%.2f)", step.Action, containerID, reason, cpuPct, memPct)
+	} else {
+		log.Printf("Aplicando %s al contenedor %s debido a %s (cpu=%.2f mem=%.2f)", step.Action, containerID, reason, cpuPct, memPct)
+
+		var err error
+		switch step.Action {
+		case rules.ACTION_THROTTLE:
+			err = throttle(containerID, step.CPUs, step.MemoryMB*1024*1024)
+		case rules.ACTION_PAUSE:
+			err = Runtime.Pause(containerID)
+		case rules.ACTION_STOP:
+			err = Runtime.Stop(containerID)
+		case rules.ACTION_REMOVE:
+			err = Runtime.Remove(containerID)
+		}
+		if err != nil {
+			log.Printf("No se pudo aplicar %s al contenedor %s: %v", step.Action, containerID, err)
+			return false
+		}
+		if step.Action == rules.ACTION_REMOVE {
+			tick.AddDeletion(containerID, reason)
+			metrics.IncDeletion(st.Class, rules.ReasonMetrics(st.Reason))
+		}
+	}
+	tick.AddAction(containerID, st.Image, st.Class, step.Action, reason, cpuPct, memPct, dryRun)
+	metrics.IncAction(st.Class, step.Action, dryRun)
+
+	enforcementLock.Lock()
+	defer enforcementLock.Unlock()
+	if step.Action == rules.ACTION_REMOVE {
+		delete(enforcement, containerID)
+	} else {
+		st.Applied = idx
+		st.Action = step.Action
+		st.DryRun = dryRun
+		st.CooldownUntil = time.Now().Add(cooldown)
+	}
+	return true
+}
+
+// finishEnforcementTick avanza la racha de los contenedores detenidos por
+// la escalera (ya no aparecen en /proc) para que puedan llegar a remove, y
+// descarta el estado de los contenedores que desaparecieron por otra causa.
+func finishEnforcementTick(tick *database.Tick, dryRun bool, cooldown time.Duration, rulesEngine *rules.Engine) {
+	type pending struct {
+		id    string
+		idx   int
+		step  rules.Step
+		state *enforceState
+	}
+	var due []pending
+
+	enforcementLock.Lock()
+	for id, st := range enforcement {
+		if st.Seen {
+			continue
+		}
+		if st.Action != rules.ACTION_STOP {
+			delete(enforcement, id)
+			continue
+		}
+		cls := rulesEngine.ClassByName(st.Class)
+		if cls == nil {
+			delete(enforcement, id)
+			continue
+		}
+		if time.Now().Before(st.CooldownUntil) {
+			continue
+		}
+		st.Streak++
+		if idx, step, _, ok := dueStep(cls.Ladder(), st); ok {
+			due = append(due, pending{id, idx, step, st})
+		}
+	}
+	enforcementLock.Unlock()
+
+	for _, p := range due {
+		applyStep(tick, dryRun, cooldown, p.id, p.idx, p.step, p.state, 0, 0)
+	}
+}
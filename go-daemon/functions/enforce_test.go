@@ -0,0 +1,112 @@
+package functions
+
+import (
+	"fmt"
+	"so1-daemon/config"
+	"so1-daemon/cruntime"
+	"so1-daemon/database"
+	"so1-daemon/rules"
+	"so1-daemon/var_const"
+	"testing"
+	"time"
+)
+
+// fakeRuntime acepta todas las acciones sin tocar ningún contenedor.
+type fakeRuntime struct {
+	cruntime.ContainerRuntime
+}
+
+func (fakeRuntime) Throttle(string, float64, int64) error { return nil }
+func (fakeRuntime) Pause(string) error                    { return nil }
+func (fakeRuntime) Stop(string) error                     { return nil }
+func (fakeRuntime) Remove(string) error                   { return nil }
+
+// runLadder ejecuta cycles ciclos con todos los contenedores en violación
+// y retorna la decisión de cada contenedor en cada ciclo. En modo real los
+// contenedores detenidos dejan de aparecer, igual que en /proc.
+func runLadder(t *testing.T, dryRun bool, cls *rules.Class, ids []string, cycles int) []string {
+	t.Helper()
+
+	prevRuntime := Runtime
+	Runtime = fakeRuntime{}
+	enforcementLock.Lock()
+	enforcement = make(map[string]*enforceState)
+	enforcementLock.Unlock()
+	t.Cleanup(func() { Runtime = prevRuntime })
+
+	cfg := config.Default()
+	cfg.DryRun = dryRun
+	cfg.Detection.Cooldown = config.Duration{}
+	engine, err := rules.Compile([]rules.Class{*cls})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Rules = engine
+
+	var decisions []string
+	for cycle := 1; cycle <= cycles; cycle++ {
+		tick := database.NewTick(time.Now())
+		groupCount := make(map[string]int)
+		var visible []string
+		for _, id := range ids {
+			enforcementLock.Lock()
+			st, ok := enforcement[id]
+			stopped := ok && st.Action == rules.ACTION_STOP && !st.DryRun
+			enforcementLock.Unlock()
+			if stopped {
+				continue
+			}
+			visible = append(visible, id)
+			if !isSanctioned(id, dryRun) {
+				groupCount[cls.GroupName()]++
+			}
+		}
+
+		beginEnforcementTick()
+		for _, id := range ids {
+			decision := "ausente"
+			for _, v := range visible {
+				if v != id {
+					continue
+				}
+				info := var_const.DockerInfo{ContainerID: id, Image: "img"}
+				decision, _, _ = enforceContainer(cfg, tick, groupCount, cls, info, true, "cpu 90.00 > 50.00", 90, 10, time.Now())
+			}
+			decisions = append(decisions, fmt.Sprintf("ciclo %d %s: %s", cycle, id, decision))
+		}
+		finishEnforcementTick(tick, dryRun, 0, cfg.Rules)
+	}
+	return decisions
+}
+
+func TestDryRunLadderMatchesRealRun(t *testing.T) {
+	cls := &rules.Class{
+		Name:     "batch",
+		MinCount: 1,
+		Match:    []rules.Match{{Images: []string{"img"}}},
+		Escalation: []rules.Step{
+			{Action: rules.ACTION_PAUSE, After: 1},
+			{Action: rules.ACTION_STOP, After: 2},
+		},
+	}
+	ids := []string{"a", "b"}
+
+	real := runLadder(t, false, cls, ids, 3)
+	dry := runLadder(t, true, cls, ids, 3)
+
+	want := []string{
+		"ciclo 1 a: pause", "ciclo 1 b: min-count",
+		"ciclo 2 a: stop", "ciclo 2 b: min-count",
+		"ciclo 3 a: ausente", "ciclo 3 b: min-count",
+	}
+	for i := range want {
+		if real[i] != want[i] {
+			t.Errorf("modo real: %q, se esperaba %q", real[i], want[i])
+		}
+		// En modo real "a" deja de aparecer al detenerse; en dry-run sigue
+		// apareciendo con la escalera completa
+		if want[i] != "ciclo 3 a: ausente" && dry[i] != real[i] {
+			t.Errorf("dry-run: %q, en modo real %q", dry[i], real[i])
+		}
+	}
+}
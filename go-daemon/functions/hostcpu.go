@@ -0,0 +1,174 @@
+package functions
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"runtime"
+	"so1-daemon/var_const"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PROC_STAT es el archivo de estadísticas de CPU del kernel.
+const PROC_STAT = "/proc/stat"
+
+// CpuTimes son los contadores acumulados (jiffies) de una línea "cpu" de
+// /proc/stat.
+type CpuTimes struct {
+	User, Nice, System, Idle, Iowait, Irq, Softirq, Steal uint64
+}
+
+// Total suma los contadores. guest y guest_nice no se suman porque el
+// kernel ya los incluye en user y nice.
+func (t CpuTimes) Total() uint64 {
+	return t.User + t.Nice + t.System + t.Idle + t.Iowait + t.Irq + t.Softirq + t.Steal
+}
+
+// CpuStat es una lectura de /proc/stat: la línea agregada "cpu" y una
+// entrada por cada línea "cpuN", indexada por N.
+type CpuStat struct {
+	Total CpuTimes
+	Cores map[int]CpuTimes
+}
+
+// ReadCpuStat lee y analiza las líneas de CPU de /proc/stat.
+func ReadCpuStat() (CpuStat, error) {
+	f, err := os.Open(PROC_STAT)
+	if err != nil {
+		return CpuStat{}, err
+	}
+	defer f.Close()
+
+	stat := CpuStat{Cores: make(map[int]CpuTimes)}
+	found := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		times, err := parseCpuTimes(fields[1:])
+		if err != nil {
+			return CpuStat{}, fmt.Errorf("línea %s de %s: %v", fields[0], PROC_STAT, err)
+		}
+
+		if fields[0] == "cpu" {
+			stat.Total = times
+			found = true
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(fields[0], "cpu"))
+		if err != nil {
+			continue
+		}
+		stat.Cores[n] = times
+	}
+	if err := scanner.Err(); err != nil {
+		return CpuStat{}, err
+	}
+	if !found {
+		return CpuStat{}, fmt.Errorf("%s no tiene la línea cpu", PROC_STAT)
+	}
+	return stat, nil
+}
+
+// parseCpuTimes convierte los valores de una línea "cpu". Los kernels
+// anteriores a 2.6.11 no publican steal; los campos faltantes quedan en 0.
+func parseCpuTimes(values []string) (CpuTimes, error) {
+	if len(values) < 4 {
+		return CpuTimes{}, fmt.Errorf("se esperaban al menos 4 campos, hay %d", len(values))
+	}
+	var v [8]uint64
+	for i := 0; i < len(v) && i < len(values); i++ {
+		n, err := strconv.ParseUint(values[i], 10, 64)
+		if err != nil {
+			return CpuTimes{}, err
+		}
+		v[i] = n
+	}
+	return CpuTimes{
+		User: v[0], Nice: v[1], System: v[2], Idle: v[3],
+		Iowait: v[4], Irq: v[5], Softirq: v[6], Steal: v[7],
+	}, nil
+}
+
+// cpuUsage calcula el reparto (%) del tiempo transcurrido entre prev y cur.
+// Retorna false si no avanzó el tiempo o algún contador retrocedió (CPU
+// desconectada y vuelta a conectar).
+func cpuUsage(prev, cur CpuTimes) (var_const.CpuUsage, bool) {
+	if cur.Total() <= prev.Total() || cur.User < prev.User || cur.Nice < prev.Nice ||
+		cur.System < prev.System || cur.Idle < prev.Idle || cur.Iowait < prev.Iowait ||
+		cur.Irq < prev.Irq || cur.Softirq < prev.Softirq || cur.Steal < prev.Steal {
+		return var_const.CpuUsage{}, false
+	}
+	total := float64(cur.Total() - prev.Total())
+	pct := func(d uint64) float64 { return float64(d) / total * 100.0 }
+
+	u := var_const.CpuUsage{
+		UserPct:   pct(cur.User - prev.User + cur.Nice - prev.Nice),
+		SystemPct: pct(cur.System - prev.System + cur.Irq - prev.Irq + cur.Softirq - prev.Softirq),
+		IdlePct:   pct(cur.Idle - prev.Idle),
+		IowaitPct: pct(cur.Iowait - prev.Iowait),
+		StealPct:  pct(cur.Steal - prev.Steal),
+	}
+	u.BusyPct = 100.0 - u.IdlePct - u.IowaitPct
+	return u, true
+}
+
+// Lectura de /proc/stat del ciclo anterior
+var (
+	prevCpuStat *CpuStat
+	hostCpuLock sync.Mutex
+)
+
+// SampleHostCpu lee /proc/stat y retorna el uso del host y de cada núcleo
+// desde la lectura anterior. En la primera llamada aún no hay diferencia y
+// retorna nil. Los núcleos sin lectura previa o con contadores reiniciados
+// se omiten.
+func SampleHostCpu() (*var_const.CpuUsage, []var_const.CoreUsage, error) {
+	cur, err := ReadCpuStat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hostCpuLock.Lock()
+	defer hostCpuLock.Unlock()
+
+	prev := prevCpuStat
+	prevCpuStat = &cur
+	if prev == nil {
+		return nil, nil, nil
+	}
+
+	var host *var_const.CpuUsage
+	if u, ok := cpuUsage(prev.Total, cur.Total); ok {
+		host = &u
+	}
+
+	cores := make([]var_const.CoreUsage, 0, len(cur.Cores))
+	for n, times := range cur.Cores {
+		p, ok := prev.Cores[n]
+		if !ok {
+			continue
+		}
+		if u, ok := cpuUsage(p, times); ok {
+			cores = append(cores, var_const.CoreUsage{Cpu: n, CpuUsage: u})
+		}
+	}
+	sort.Slice(cores, func(i, j int) bool { return cores[i].Cpu < cores[j].Cpu })
+	return host, cores, nil
+}
+
+// HostCpuCount retorna la cantidad de núcleos del host según la última
+// lectura de /proc/stat, o runtime.NumCPU si aún no hay ninguna.
+func HostCpuCount() int {
+	hostCpuLock.Lock()
+	defer hostCpuLock.Unlock()
+	if prevCpuStat != nil && len(prevCpuStat.Cores) > 0 {
+		return len(prevCpuStat.Cores)
+	}
+	return runtime.NumCPU()
+}
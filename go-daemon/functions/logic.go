@@ -1,13 +1,15 @@
 package functions
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"so1-daemon/config"
 	"so1-daemon/database"
+	"so1-daemon/metrics"
+	"so1-daemon/rules"
 	"so1-daemon/utils"
 	"so1-daemon/var_const"
-	"strings"
 	"time"
 )
 
@@ -16,19 +18,24 @@ import (
 // según políticas de CPU, memoria y reglas de balance mínimo.
 //
 // Flujo general:
-// 1) Obtiene el mapeo PID ↔ Contenedor Docker
+// 1) Obtiene el mapeo PID ↔ Contenedor desde el runtime (Docker, containerd, Podman)
 // 2) Clasifica procesos como contenedores reales, shims o genéricos
-// 3) Cuenta contenedores low / high según la imagen
-// 4) Calcula uso de CPU y memoria
-// 5) Aplica reglas de decisión y ejecuta acciones (docker rm)
-func DecideAndAct(containers []var_const.ProcProcess) {
+// 3) Clasifica los contenedores con el motor de reglas y cuenta por grupo
+// 4) Calcula uso de CPU y memoria (la memoria de los contenedores se lee de su cgroup)
+// 5) Evalúa violaciones sostenidas (ventana / EWMA) y aplica la escalera de sanciones de cada clase (throttle, pause, stop, remove)
+// 6) Registra cada muestra con su decisión (acción aplicada o motivo por el que no se actuó)
+//
+// cfg es la configuración vigente al iniciar el ciclo; se recibe como
+// parámetro para que una recarga a mitad del ciclo no mezcle valores.
+// Las filas a guardar se agregan a tick, que ProcessOnce confirma al final.
+func DecideAndAct(cfg *config.Config, tick *database.Tick, cont var_const.ProcCont) {
 
-	// 1. Construcción del mapa PID → Información Docker
-	// Obtiene los contenedores activos usando docker inspect
+	// 1. Construcción del mapa PID → Información del contenedor
+	// Obtiene los contenedores activos desde el runtime configurado
 	// El mapa permite relacionar un PID con su contenedor real
-	dmap, err := GetDockerPidMap()
+	dmap, err := Runtime.PidMap()
 	if err != nil {
-		log.Printf("Advertencia: no se puede obtener el mapa de Docker: %v", err)
+		log.Printf("Advertencia: no se puede obtener el mapa de %s: %v", Runtime.Name(), err)
 	}
 
 	// 2. Clasificación de procesos detectados
@@ -38,26 +45,22 @@ func DecideAndAct(containers []var_const.ProcProcess) {
 		Docker var_const.DockerInfo
 	}
 	var detected []CInfo
-	for _, p := range containers {
+	for _, p := range cont.Containers {
 
 		// Caso 1: PID corresponde directamente a un contenedor Docker
 		if d, ok := dmap[p.Pid]; ok {
 			detected = append(detected, CInfo{Proc: p, Docker: d})
 		} else {
-			// Caso 2: Proceso intermedio (containerd-shim)
+			// Caso 2: Proceso intermedio (containerd-shim, conmon)
 			// Se intenta extraer el Container ID desde la línea de comandos
-			if p.Name == "containerd-shim" {
-
-				containerID := ExtractContainerID(p.Cmdline)
+			if containerID := Runtime.ShimContainerID(p); containerID != "" {
 
-				if containerID != "" {
-					// **Buscar la imagen real usando el Container ID**
-					dockerInfo, err := GetDockerInfoByID(containerID) // Función auxiliar
+				// **Buscar la imagen real usando el Container ID**
+				dockerInfo, err := Runtime.InfoByID(containerID)
 
-					if err == nil {
-						detected = append(detected, CInfo{Proc: p, Docker: dockerInfo})
-						continue
-					}
+				if err == nil {
+					detected = append(detected, CInfo{Proc: p, Docker: dockerInfo})
+					continue
 				}
 			}
 
@@ -75,39 +78,46 @@ func DecideAndAct(containers []var_const.ProcProcess) {
 
 	}
 
-	// 3. Conteo de contenedores LOW / HIGH
-	lowCount := 0
-	highCount := 0
-	for _, c := range detected {
-
-		img := strings.ToLower(c.Docker.Image)
-
-		isLow := strings.Contains(img, "low_img")
-		isHighCPU := strings.Contains(img, "high_cpu_img")
-		isHighRAM := strings.Contains(img, "high_mem_img")
-
-		if isHighCPU || isHighRAM {
-			highCount++
-		} else if isLow {
-			lowCount++
+	// 3. Clasificación según las reglas y conteo por grupo
+	// Cada contenedor se clasifica una sola vez; la clase se reutiliza en
+	// la evaluación de reglas (paso 5)
+	classes := make([]*rules.Class, len(detected))
+	groupCount := make(map[string]int)
+	for i, c := range detected {
+		classes[i] = cfg.Rules.Classify(c.Docker)
+		// Los contenedores pausados o detenidos por la escalera de sanciones
+		// ya no cuentan como activos
+		if classes[i] != nil && !classes[i].Protected && !isSanctioned(c.Docker.ContainerID, cfg.DryRun) {
+			groupCount[classes[i].GroupName()]++
 		}
-
 	}
 
-	log.Printf("Información: Bajo consumo=%d Alto Consumo=%d", lowCount, highCount)
+	log.Printf("Información: contenedores por grupo %v", groupCount)
 
 	// 4. Preparación para cálculo de CPU y memoria
 
-	totalJiffies, _ := ReadTotalJiffies()
+	hostCpus := HostCpuCount()
 	now := time.Now()
 	type decisionCandidate struct {
-		C   CInfo
-		Mem float64
-		Cpu float64
+		C     CInfo
+		Class *rules.Class
+		Mem   float64
+		Cpu   var_const.CpuViews
+		Usage var_const.PidCpuSample // historial para la detección sostenida
+		// Recursos del cgroup y suma de sus tareas (solo contenedores)
+		Cgroup *var_const.CgroupStats
+		Tasks  TaskUsage
+
+		// Resultado de la evaluación (paso 5), guardado con la muestra
+		Decision string
+		Reason   string
 	}
-	var candidates []decisionCandidate
+	var candidates []*decisionCandidate
+	alive := make(map[string]bool)
+	liveSamples := make(map[var_const.SampleKey]bool)
+	taskUsage := make(map[string]TaskUsage)
 
-	for _, c := range detected {
+	for i, c := range detected {
 
 		// Parseo del porcentaje de memoria
 
@@ -117,117 +127,188 @@ func DecideAndAct(containers []var_const.ProcProcess) {
 		if c.Docker.ContainerID == "" {
 			log.Printf("Skipping cgroup read for non-docker PID %d", c.Proc.Pid)
 
-			procTime, err := ReadProcPidTime(c.Proc.Pid)
-
-			if err != nil {
+			// La serie se identifica por PID; el inicio del proceso detecta
+			// PIDs reutilizados
+			key := var_const.SampleKey{Pid: c.Proc.Pid, Unit: var_const.CPU_UNIT_TICKS}
+			liveSamples[key] = true
+
+			var cpu var_const.CpuViews
+			cpuOk := false
+			if st, err := ReadProcStat(c.Proc.Pid); err == nil {
+				var raw float64
+				raw, cpuOk = CalcCpuPercent(key, st.Utime+st.Stime, st.StartTime, now)
+				cpu = cpuViews(raw, hostCpus, 0)
+			} else {
 				log.Printf("Warning: failed to read proc time for PID %d: %v", c.Proc.Pid, err)
-				procTime = 0
 			}
-			cpuPct := CalcCpuPercent(c.Proc.Pid, procTime, totalJiffies, now)
+			usage := RecordUsage(key, cpu, cpuOk, memf, now, cfg.Detection)
 
-			candidates = append(candidates, decisionCandidate{C: c, Mem: memf, Cpu: cpuPct})
-			database.InsertContainerRecord(c.Docker.ContainerID, c.Proc.Pid, c.Docker.Image, cpuPct, memf)
+			candidates = append(candidates, &decisionCandidate{C: c, Class: classes[i], Mem: memf, Cpu: cpu, Usage: usage})
 			continue
 		}
 
 		// --- NUEVA LECTURA DEL CGROUP ---
 		// Esto lee el tiempo total de CPU en nanosegundos (la fuente de datos de Docker).
-		procTime, err := ReadCgroupCpuTime(c.Docker.ContainerID)
+		// El directorio se resuelve desde /proc/<pid>/cgroup del proceso
+		// principal (no del shim, que vive en el cgroup del runtime) y se
+		// guarda en caché por contenedor
+		alive[c.Docker.ContainerID] = true
+		mainPid := c.Docker.Pid
+		if mainPid == 0 {
+			mainPid = c.Proc.Pid
+		}
+		paths, cgErr := ResolveCgroup(c.Docker.ContainerID, mainPid, Runtime.CgroupDirs(c.Docker))
+
+		// Todas las tareas del contenedor, no solo el proceso que publica
+		// el módulo del kernel (los workers de un proceso que hace fork no
+		// aparecen en PROC_CONT). Se calcula una vez por contenedor.
+		tasks, ok := taskUsage[c.Docker.ContainerID]
+		if !ok {
+			if pids, err := ContainerTasks(paths, mainPid); err == nil {
+				tasks = SumTasks(pids)
+			} else {
+				log.Printf("Advertencia: no se pudieron listar las tareas de %s: %v", c.Docker.ContainerID, err)
+			}
+			taskUsage[c.Docker.ContainerID] = tasks
+		}
 
-		if err != nil {
-			log.Printf("Advertencia: no se ha podido leer el tiempo de CPU del grupo de control para %s: %v. Se establece la CPU en 0.", c.Docker.ContainerID, err)
-			procTime = 0
+		var procTime uint64
+		err := cgErr
+		if err == nil {
+			procTime, err = ReadCgroupCpuTime(paths)
 		}
 
-		// 2. Usar este valor para el cálculo.
-		cpuPct := CalcCpuPercent(c.Proc.Pid, procTime, totalJiffies, now)
-		candidates = append(candidates, decisionCandidate{C: c, Mem: memf, Cpu: cpuPct})
+		// 2. Usar este valor para el cálculo. Si el cgroup no se puede leer
+		// se usa la suma de utime + stime de las tareas, en una serie
+		// propia (otra unidad); sin ninguna lectura la muestra se omite en
+		// lugar de registrarse como 0
+		key := var_const.SampleKey{ContainerID: c.Docker.ContainerID, Unit: var_const.CPU_UNIT_NS}
+		var cpuPct float64
+		cpuOk := false
+		switch {
+		case err == nil:
+			cpuPct, cpuOk = CalcCpuPercent(key, procTime, 0, now)
+		case tasks.Tasks > 0:
+			key.Unit = var_const.CPU_UNIT_TICKS
+			cpuPct, cpuOk = CalcCpuPercent(key, tasks.CpuTicks, 0, now)
+			log.Printf("Advertencia: no se ha podido leer el tiempo de CPU del grupo de control para %s: %v. Se suma el de sus %d tareas.", c.Docker.ContainerID, err, tasks.Tasks)
+		default:
+			log.Printf("Advertencia: no se ha podido leer el tiempo de CPU del grupo de control para %s: %v. Se omite la muestra de CPU.", c.Docker.ContainerID, err)
+		}
+		liveSamples[key] = true
+
+		// La memoria del contenedor es la de todo su cgroup (sin caché
+		// inactiva) respecto de la memoria total del host; si no se puede
+		// leer se usa el RSS sumado de sus tareas y, en último caso, el
+		// mem_pct del proceso principal
+		var cg *var_const.CgroupStats
+		if st, err := ReadCgroupStats(paths); err == nil {
+			cg = &st
+			if cont.MemTotalKb > 0 {
+				memf = float64(st.MemoryWorkingSet) / float64(cont.MemTotalKb*1024) * 100.0
+			}
+		} else if tasks.Tasks > 0 && cont.MemTotalKb > 0 {
+			memf = float64(tasks.RssKb) / float64(cont.MemTotalKb) * 100.0
+			log.Printf("Advertencia: no se pudieron leer los recursos del cgroup de %s: %v. Se usa el RSS de sus %d tareas.", c.Docker.ContainerID, err, tasks.Tasks)
+		} else {
+			log.Printf("Advertencia: no se pudieron leer los recursos del cgroup de %s: %v. Se usa el mem_pct del PID %d.", c.Docker.ContainerID, err, c.Proc.Pid)
+		}
 
-		// Registrar en base de datos
-		database.InsertContainerRecord(c.Docker.ContainerID, c.Proc.Pid, c.Docker.Image, cpuPct, memf)
+		// El %CPU se expresa también respecto de los núcleos del host y de
+		// la cuota del contenedor; cada clase elige la escala de su umbral
+		var quota float64
+		if cg != nil {
+			quota = cg.CpuLimit
+		}
+		cpu := cpuViews(cpuPct, hostCpus, quota)
+
+		usage := RecordUsage(key, cpu, cpuOk, memf, now, cfg.Detection)
+		candidates = append(candidates, &decisionCandidate{C: c, Class: classes[i], Mem: memf, Cpu: cpu, Usage: usage, Cgroup: cg, Tasks: tasks})
 	}
 
-	// 5. Evaluación de reglas y acciones
+	pruneCgroupCache(alive)
+	PruneSamples(liveSamples)
 
-	for _, cand := range candidates {
-		img := strings.ToLower(cand.C.Docker.Image)
+	// 5. Evaluación de reglas y acciones
+	// Cada clase tiene una escalera de sanciones (throttle → pause → stop →
+	// remove); un contenedor sube un escalón al acumular los ciclos
+	// consecutivos en violación que exige ese escalón. Cada candidato
+	// termina con una decisión y su razón, que se guardan con la muestra.
 
-		isLow := strings.Contains(img, "low_img")
-		isHighCPU := strings.Contains(img, "high_cpu_img")
-		isHighRAM := strings.Contains(img, "high_mem_img")
+	beginEnforcementTick()
 
-		shouldKill := false
-		reason := ""
-		if isHighCPU {
-			log.Println("CPU: ", cand.Cpu, " RAM: ", cand.Mem, " RASONAMIENTO CPU: ", isHighCPU && cand.Cpu > var_const.CPU_THRESHOLD,
-				" RASONAMIENTO RAM: ", isHighRAM && cand.Mem > var_const.MEM_THRESHOLD)
+	for _, cand := range candidates {
+		cls := cand.Class
+		cand.Decision = DECISION_NONE
 
+		// Contenedor sin clasificar: no se le aplica ninguna política
+		if cls == nil {
+			cand.Decision = DECISION_UNCLASSIFIED
+			continue
 		}
-		// Reglas de eliminación
-		if isHighCPU && cand.Cpu > var_const.CPU_THRESHOLD {
-			shouldKill = true
-			reason = fmt.Sprintf("cpu %.2f > %.2f", cand.Cpu, var_const.CPU_THRESHOLD)
-		}
-		if isHighRAM && cand.Mem > var_const.MEM_THRESHOLD {
-			shouldKill = true
-			reason = fmt.Sprintf("mem %.2f > %.2f", cand.Mem, var_const.MEM_THRESHOLD)
-		}
-		if isLow && (cand.Cpu > var_const.CPU_THRESHOLD || cand.Mem > var_const.MEM_THRESHOLD) {
-			shouldKill = true
-			reason = "El contenedor bajo ha superado el umbral."
+
+		// Reglas de la clase (umbrales propios o globales) evaluadas sobre
+		// el historial del contenedor según el modo de detección
+		violating, reason := SustainedViolation(cfg, cls, cand.Usage)
+		switch {
+		case cls.Protected:
+			cand.Decision, cand.Reason = DECISION_PROTECTED, "clase protegida "+cls.Name
+		case !hasCpu(cand.Usage.History):
+			cand.Decision, cand.Reason = DECISION_WARMUP, "primera muestra de la serie"
 		}
-		if shouldKill {
-			if cand.C.Docker.ContainerID == "" {
 
+		if cand.C.Docker.ContainerID == "" {
+			if violating {
 				log.Printf("El candidato pid %d no es un contenedor Docker o no hay ningún ID disponible, omitir la eliminación.", cand.C.Proc.Pid)
-				continue
-			}
-
-			if strings.Contains(strings.ToLower(cand.C.Docker.Image), "grafana") || strings.Contains(strings.ToLower(cand.C.Docker.Name), "grafana") {
-				log.Printf("Omitiendo la eliminación del contenedor grafana %s", cand.C.Docker.ContainerID)
-				continue
-			}
-			if isHighCPU || isHighRAM {
-				if highCount <= var_const.MIN_HIGH_CONTAINERS {
-					log.Printf("Se eliminaría %s, pero se infringiría MIN_HIGH_CONTAINERS (%d)", cand.C.Docker.ContainerID, var_const.MIN_HIGH_CONTAINERS)
-					continue
-				}
-			} else if isLow {
-				if lowCount <= var_const.MIN_LOW_CONTAINERS {
-					log.Printf(
-						"Se eliminaría %s, pero se infringiría MIN_LOW_CONTAINERS (%d)",
-						cand.C.Docker.ContainerID,
-						var_const.MIN_LOW_CONTAINERS,
-					)
-					continue
-				}
-
-			} else {
-				if lowCount <= var_const.MIN_LOW_CONTAINERS {
-					log.Printf(
-						"Se eliminaría %s (imagen sin clasificar), pero se infringiría MIN_LOW_CONTAINERS (%d).",
-						cand.C.Docker.ContainerID,
-						var_const.MIN_LOW_CONTAINERS,
-					)
-					continue
-				}
+				cand.Decision, cand.Reason = DECISION_NOT_CONTAINER, reason
 			}
+			continue
+		}
 
-			log.Printf("Eliminación del contenedor %s debido a %s (cpu=%.2f mem=%.2f)", cand.C.Docker.ContainerID, reason, cand.Cpu, cand.Mem)
-			out, err := utils.RunCommand("docker", "rm", "-f", cand.C.Docker.ContainerID)
-			if err != nil {
-				log.Printf("No se pudo eliminar el contenedor %s: %v | salida: %s", cand.C.Docker.ContainerID, err, out)
-			} else {
-				database.InsertDeletion(cand.C.Docker.ContainerID, reason)
+		if decision, why, ok := enforceContainer(cfg, tick, groupCount, cls, cand.C.Docker, violating, reason, cand.Cpu.Raw, cand.Mem, now); ok {
+			cand.Decision, cand.Reason = decision, why
+		}
+	}
 
-				if isHighCPU || isHighRAM {
-					highCount--
-				} else {
-					lowCount--
-				}
-			}
+	// Registro de las muestras con su decisión y estado para la API HTTP
+	status := make([]var_const.ContainerSample, 0, len(candidates))
+	for _, cand := range candidates {
+		className := ""
+		if cand.Class != nil {
+			className = cand.Class.Name
 		}
+		// Memoria del contenedor completo si se pudieron sumar sus tareas
+		rss, vsz := cand.C.Proc.RssKb, cand.C.Proc.VszKb
+		if cand.Tasks.Tasks > 0 {
+			rss, vsz = cand.Tasks.RssKb, cand.Tasks.VszKb
+		}
+		// La misma muestra se guarda en la base y se publica en la API
+		sample := var_const.ContainerSample{
+			ContainerID: cand.C.Docker.ContainerID,
+			Pid:         cand.C.Proc.Pid,
+			Name:        cand.C.Docker.Name,
+			Image:       cand.C.Docker.Image,
+			Class:       className,
+			State:       cand.C.Proc.State,
+			RssKb:       rss,
+			VszKb:       vsz,
+			Tasks:       cand.Tasks.Tasks,
+			CpuPct:      cand.Cpu.Raw,
+			CpuHostPct:  cand.Cpu.Host,
+			CpuQuotaPct: cand.Cpu.Quota,
+			MemPct:      cand.Mem,
+			Decision:    cand.Decision,
+			Reason:      cand.Reason,
+			Cgroup:      cand.Cgroup,
+			Ts:          tick.Ts,
+		}
+		tick.AddContainerRecord(sample)
+		status = append(status, sample)
 	}
+	setContainersSnapshot(status)
+
+	// Contenedores detenidos por la escalera que esperan su eliminación
+	finishEnforcementTick(tick, cfg.DryRun, cfg.Detection.Cooldown.Duration, cfg.Rules)
 
 }
 
@@ -238,63 +319,115 @@ func DecideAndAct(containers []var_const.ProcProcess) {
 // 2) Registra métricas de memoria y cantidad de procesos en la base de datos
 // 3) Lee información de contenedores desde /proc/continfo
 // 4) Analiza el estado de los contenedores y ejecuta acciones correctivas
+// 5) Confirma todas las filas del ciclo en una sola transacción
 //
 // Esta función es invocada periódicamente por el daemon principal
 // mediante un ticker (por ejemplo, cada 20 segundos).
-func ProcessOnce() error {
+func ProcessOnce() (err error) {
+	// Duración del ciclo para el histograma de Prometheus
+	start := time.Now()
+	defer func() {
+		metrics.ObserveTick(time.Since(start), err != nil)
+	}()
+
+	// Copia de la configuración activa para todo el ciclo
+	cfg := config.Get()
+
+	// Todas las filas del ciclo se escriben en una sola transacción al
+	// terminar, incluso si el ciclo se interrumpe por un error de lectura
+	tick := database.NewTick(start)
+	defer func() {
+		if cerr := tick.Commit(); cerr != nil {
+			err = errors.Join(err, fmt.Errorf("guardar ciclo: %v", cerr))
+		}
+	}()
 
 	// 1. Lectura de métricas generales del sistema
 
 	// Lee el archivo /proc/sysinfo generado por el módulo del kernel
-	sysB, err := utils.ReadProcFile(var_const.PROC_SYS)
+	sysB, err := utils.ReadProcFile(cfg.ProcSys)
 	if err != nil {
 		return fmt.Errorf("leer sys proc: %v", err)
 	}
 
-	// Limpia el JSON generado por el kernel (comas finales, formatos no estándar)
-	sysB = utils.SanitizeJSON(sysB)
-
-	// Estructura destino para deserializar la información del sistema
-	var sys var_const.ProcSys
-
-	// Convierte el JSON en una estructura Go
-	if err := json.Unmarshal(sysB, &sys); err != nil {
+	// Valida el JSON del módulo según su schema_version; en modo tolerante
+	// se descartan solo los registros inválidos
+	tolerant := cfg.ProcParse == config.PROC_PARSE_TOLERANT
+	sys, skipped, err := ParseProcSys(cfg.ProcSys, sysB, tolerant)
+	reportSkipped(cfg.ProcSys, skipped)
+	if err != nil {
 		return fmt.Errorf("analizar sys json: %v", err)
 	}
 
-	// Inserta métricas de memoria del sistema en la base de datos
-	database.InsertSysMetrics(
+	// Uso de CPU del host y de cada núcleo desde el ciclo anterior; un
+	// error de lectura no detiene el ciclo
+	hostCpu, cores, cpuErr := SampleHostCpu()
+	if cpuErr != nil {
+		log.Printf("Advertencia: no se pudo leer %s: %v", PROC_STAT, cpuErr)
+	}
+
+	// Métricas de memoria y CPU del sistema
+	tick.AddSysMetrics(
 		sys.MemTotalKb,
 		sys.MemFreeKb,
 		sys.MemUsedKb,
+		hostCpu,
 	)
+	tick.AddCpuCores(cores)
+
+	// Registra la cantidad total de procesos activos y su estado
+	states := CountStates(sys.Processes)
+	tick.AddProcessCount(len(sys.Processes), states)
+	checkStateAlerts(cfg.StateAlerts, states)
+
+	// Instantáneas por proceso (top-N) para investigar el consumo del host
+	if cfg.ProcessSnapshots.Enabled {
+		recordProcesses(tick, cfg.ProcessSnapshots, sys.Processes, start)
+	}
 
-	// Registra la cantidad total de procesos activos
-	database.InsertProcessCount(len(sys.Processes))
+	setSystemSnapshot(var_const.SystemStatus{
+		MemTotalKb: sys.MemTotalKb,
+		MemFreeKb:  sys.MemFreeKb,
+		MemUsedKb:  sys.MemUsedKb,
+		Processes:  len(sys.Processes),
+		States:     states,
+		Cpu:        hostCpu,
+		Cores:      cores,
+	})
 
 	// 2. Lectura de información de contenedores
 
 	// Lee el archivo /proc/continfo generado por el módulo del kernel
-	contB, err := utils.ReadProcFile(var_const.PROC_CONT)
+	contB, err := utils.ReadProcFile(cfg.ProcCont)
 	if err != nil {
 		return fmt.Errorf("leer cont proc: %v", err)
 	}
 
-	// Limpia el JSON de salida del módulo del kernel
-	contB = utils.SanitizeJSON(contB)
-
-	// Estructura destino para deserializar la información de contenedores
-	var cont var_const.ProcCont
-
-	// Convierte el JSON en la estructura Go correspondiente
-	if err := json.Unmarshal(contB, &cont); err != nil {
+	cont, skipped, err := ParseProcCont(cfg.ProcCont, contB, tolerant)
+	reportSkipped(cfg.ProcCont, skipped)
+	if err != nil {
 		return fmt.Errorf("analizar cont json: %v", err)
 	}
 
 	// 3. Análisis y toma de decisiones
 
 	// Analiza el consumo de recursos de los contenedores
-	DecideAndAct(cont.Containers)
+	DecideAndAct(cfg, tick, cont)
+
+	snapshotLock.Lock()
+	snapshot.Timestamp = time.Now()
+	snapshotLock.Unlock()
 
 	return nil
 }
+
+// reportSkipped registra los registros descartados de un archivo /proc.
+func reportSkipped(file string, skipped []ProcRecordError) {
+	if len(skipped) == 0 {
+		return
+	}
+	for _, e := range skipped {
+		log.Printf("Advertencia: registro descartado: %v", &e)
+	}
+	metrics.AddProcSkipped(file, len(skipped))
+}
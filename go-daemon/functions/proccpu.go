@@ -0,0 +1,139 @@
+package functions
+
+import (
+	"encoding/binary"
+	"log"
+	"os"
+	"so1-daemon/var_const"
+	"strconv"
+	"time"
+)
+
+// PROC_AUXV es el vector auxiliar que el kernel entrega al proceso; de
+// aquí se obtiene AT_CLKTCK, el mismo valor que sysconf(_SC_CLK_TCK).
+const PROC_AUXV = "/proc/self/auxv"
+
+const (
+	AT_NULL    = 0
+	AT_CLKTCK  = 17
+	DEFAULT_HZ = 100
+)
+
+// USER_HZ es la frecuencia de los ticks de /proc/[pid]/stat. Se lee de
+// AT_CLKTCK al arrancar; si no está disponible se usa DEFAULT_HZ, que es
+// el valor fijo en x86 y arm64.
+var USER_HZ = clockTicks()
+
+func clockTicks() uint64 {
+	hz, err := readAuxv(PROC_AUXV, AT_CLKTCK)
+	if err != nil || hz == 0 {
+		log.Printf("Advertencia: no se pudo leer AT_CLKTCK de %s (%v); se usa USER_HZ=%d", PROC_AUXV, err, DEFAULT_HZ)
+		return DEFAULT_HZ
+	}
+	return hz
+}
+
+// readAuxv busca la entrada key en el vector auxiliar: pares (tipo,
+// valor) de palabras nativas terminados en AT_NULL.
+func readAuxv(path string, key uint64) (uint64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	word := strconv.IntSize / 8
+	read := func(p []byte) uint64 {
+		if word == 4 {
+			return uint64(binary.NativeEndian.Uint32(p))
+		}
+		return binary.NativeEndian.Uint64(p)
+	}
+	for i := 0; i+2*word <= len(b); i += 2 * word {
+		switch read(b[i:]) {
+		case key:
+			return read(b[i+word:]), nil
+		case AT_NULL:
+			return 0, os.ErrNotExist
+		}
+	}
+	return 0, os.ErrNotExist
+}
+
+// unitsPerSecond retorna cuántas unidades del contador hay en un segundo.
+func unitsPerSecond(unit string) float64 {
+	if unit == var_const.CPU_UNIT_TICKS {
+		return float64(USER_HZ)
+	}
+	return 1e9
+}
+
+// cpuPercent es el cálculo común del %CPU (100% por núcleo, igual que
+// docker stats) de contenedores, procesos sin contenedor y procesos del
+// host: la diferencia del contador value respecto de la lectura anterior
+// en c, convertida a segundos según unit, por segundo real transcurrido.
+// Actualiza c y retorna false si el valor no es utilizable:
+//
+//   - primera lectura (c vacío)
+//   - start distinto al anterior: el PID fue reutilizado por otro proceso
+//     (restarted es true y la serie debe empezar de cero)
+//   - contador menor al anterior (contenedor recreado con el mismo ID):
+//     se toma como nueva línea base
+//
+// Si c ya se calculó en el ciclo now se retorna el mismo resultado.
+func cpuPercent(c *var_const.CpuCounter, unit string, value, start uint64, now time.Time) (pct float64, ok, restarted bool) {
+	switch {
+	case !c.Timestamp.IsZero() && c.Timestamp.Equal(now):
+		return c.LastPct, c.LastOk, false
+	case c.Timestamp.IsZero() || c.StartTime != start:
+		restarted = !c.Timestamp.IsZero()
+		*c = var_const.CpuCounter{Value: value, StartTime: start, Timestamp: now}
+		return 0.0, false, restarted
+	case value < c.Value:
+		*c = var_const.CpuCounter{Value: value, StartTime: start, Timestamp: now}
+		return 0.0, false, false
+	}
+
+	// Tiempo real transcurrido en segundos
+	dTime := now.Sub(c.Timestamp).Seconds()
+	if dTime <= 0 {
+		return 0.0, false, false
+	}
+
+	// Tiempo de CPU consumido (en segundos) por segundo real; puede superar
+	// 100% si se usan varios núcleos
+	dProc := float64(value-c.Value) / unitsPerSecond(unit)
+	pct = dProc / dTime * 100.0
+
+	*c = var_const.CpuCounter{Value: value, StartTime: start, Timestamp: now, LastPct: pct, LastOk: true}
+	return pct, true, false
+}
+
+// CalcCpuPercent calcula con cpuPercent el %CPU de la serie key de
+// PrevSamples. Si el PID fue reutilizado la serie, incluido su historial,
+// empieza de cero; si el contador se reinició se conserva el historial.
+//
+// Una lectura fallida no debe registrarse: la serie conserva la última
+// lectura válida y la siguiente diferencia abarca todo el intervalo.
+func CalcCpuPercent(key var_const.SampleKey, cpuTime, startTime uint64, now time.Time) (float64, bool) {
+	var_const.PrevSamplesLock.Lock()
+	defer var_const.PrevSamplesLock.Unlock()
+
+	s := var_const.PrevSamples[key]
+	pct, ok, restarted := cpuPercent(&s.CpuCounter, key.Unit, cpuTime, startTime, now)
+	if restarted {
+		s = var_const.PidCpuSample{CpuCounter: s.CpuCounter}
+	}
+	var_const.PrevSamples[key] = s
+	return pct, ok
+}
+
+// PruneSamples descarta las series de PrevSamples que no están en alive
+// (contenedores y procesos que ya no existen).
+func PruneSamples(alive map[var_const.SampleKey]bool) {
+	var_const.PrevSamplesLock.Lock()
+	defer var_const.PrevSamplesLock.Unlock()
+	for key := range var_const.PrevSamples {
+		if !alive[key] {
+			delete(var_const.PrevSamples, key)
+		}
+	}
+}
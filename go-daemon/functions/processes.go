@@ -0,0 +1,141 @@
+package functions
+
+import (
+	"log"
+	"so1-daemon/config"
+	"so1-daemon/database"
+	"so1-daemon/utils"
+	"so1-daemon/var_const"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Contadores previos de los procesos de PROC_SYS. Se guardan aparte de
+// PrevSamples porque allí los contenedores se identifican por su ID y los
+// procesos sin contenedor usan /proc/<pid>/stat, pero el cálculo es el
+// mismo (cpuPercent).
+var (
+	procCpuPrev = make(map[int]var_const.CpuCounter)
+	procCpuLock sync.Mutex
+)
+
+// hostProcessCpu calcula el %CPU de cada proceso a partir de la diferencia
+// de proc_jiffies entre ciclos. El módulo del kernel publica
+// task->utime + task->stime, que en kernels modernos está en nanosegundos.
+// La primera muestra de un PID, o una lectura menor que la anterior (PID
+// reutilizado), retorna 0. Los PIDs que ya no existen se descartan.
+func hostProcessCpu(procs []var_const.ProcProcess, now time.Time) map[int]float64 {
+	procCpuLock.Lock()
+	defer procCpuLock.Unlock()
+
+	cpu := make(map[int]float64, len(procs))
+	seen := make(map[int]bool, len(procs))
+	for _, p := range procs {
+		seen[p.Pid] = true
+		c := procCpuPrev[p.Pid]
+		if pct, ok, _ := cpuPercent(&c, var_const.CPU_UNIT_NS, p.ProcJiffies, 0, now); ok {
+			cpu[p.Pid] = pct
+		}
+		procCpuPrev[p.Pid] = c
+	}
+
+	for pid := range procCpuPrev {
+		if !seen[pid] {
+			delete(procCpuPrev, pid)
+		}
+	}
+	return cpu
+}
+
+// topProcesses ordena las muestras de mayor a menor según sortBy y retorna
+// las primeras n (todas si n es 0).
+func topProcesses(samples []var_const.ProcessSample, sortBy string, n int) []var_const.ProcessSample {
+	key := func(p var_const.ProcessSample) float64 { return p.MemPct }
+	switch sortBy {
+	case config.PROC_SORT_RSS:
+		key = func(p var_const.ProcessSample) float64 { return float64(p.RssKb) }
+	case config.PROC_SORT_CPU:
+		key = func(p var_const.ProcessSample) float64 { return p.CpuPct }
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return key(samples[i]) > key(samples[j]) })
+
+	if n > 0 && len(samples) > n {
+		samples = samples[:n]
+	}
+	return samples
+}
+
+// recordProcesses agrega al ciclo las instantáneas de los procesos del host
+// seleccionados por process_snapshots.
+func recordProcesses(tick *database.Tick, ps config.ProcessSnapshots, procs []var_const.ProcProcess, now time.Time) {
+	cpu := hostProcessCpu(procs, now)
+
+	samples := make([]var_const.ProcessSample, 0, len(procs))
+	for _, p := range procs {
+		memPct, _ := utils.ParseMemPct(p.MemPct)
+		samples = append(samples, var_const.ProcessSample{
+			Pid:     p.Pid,
+			Name:    p.Name,
+			Cmdline: p.Cmdline,
+			RssKb:   p.RssKb,
+			VszKb:   p.VszKb,
+			MemPct:  memPct,
+			State:   p.State,
+			CpuPct:  cpu[p.Pid],
+		})
+	}
+
+	for _, s := range topProcesses(samples, ps.SortBy, ps.TopN) {
+		tick.AddProcessSample(s)
+	}
+}
+
+// CountStates agrupa los procesos por el carácter de estado del kernel.
+func CountStates(procs []var_const.ProcProcess) var_const.ProcessStates {
+	var st var_const.ProcessStates
+	for _, p := range procs {
+		switch p.State {
+		case "R":
+			st.Running++
+		case "S":
+			st.Sleeping++
+		case "D":
+			st.DiskSleep++
+		case "T", "t":
+			st.Stopped++
+		case "Z":
+			st.Zombie++
+		case "I":
+			st.Idle++
+		default:
+			st.Other++
+		}
+	}
+	return st
+}
+
+// Alertas de estado activas; la advertencia se registra al superar el
+// límite y al volver por debajo, no en cada ciclo.
+var (
+	zombieAlert    bool
+	diskSleepAlert bool
+)
+
+// checkStateAlerts compara los conteos de zombies y procesos en estado D
+// con los límites de state_alerts.
+func checkStateAlerts(limits config.StateAlerts, st var_const.ProcessStates) {
+	stateAlert(&zombieAlert, "zombie (Z)", st.Zombie, limits.Zombie)
+	stateAlert(&diskSleepAlert, "en espera ininterrumpible (D)", st.DiskSleep, limits.DiskSleep)
+}
+
+func stateAlert(active *bool, desc string, n, limit int) {
+	exceeded := limit > 0 && n > limit
+	switch {
+	case exceeded && !*active:
+		log.Printf("Advertencia: %d procesos %s superan el límite de %d", n, desc, limit)
+	case !exceeded && *active:
+		log.Printf("Procesos %s de nuevo dentro del límite (%d)", desc, n)
+	}
+	*active = exceeded
+}
@@ -0,0 +1,385 @@
+package functions
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"so1-daemon/var_const"
+	"strconv"
+	"strings"
+)
+
+// PROC_SCHEMA_VERSION es la versión del formato JSON de PROC_SYS y
+// PROC_CONT que entiende el daemon (campo schema_version). Los módulos
+// anteriores no publican el campo (versión 0): su salida tiene comas
+// finales y cadenas sin escapar, por lo que solo se acepta en modo
+// tolerante.
+const PROC_SCHEMA_VERSION = 1
+
+// ProcRecordError indica qué registro de un archivo /proc no cumple el
+// formato.
+type ProcRecordError struct {
+	File  string
+	Index int // posición en el arreglo de procesos, desde 0
+	Line  int // línea del archivo; 0 si se desconoce
+	Pid   int // 0 si no se pudo leer
+	Err   error
+}
+
+func (e *ProcRecordError) Error() string {
+	msg := fmt.Sprintf("%s: registro %d", e.File, e.Index)
+	if e.Line > 0 {
+		msg += fmt.Sprintf(" (línea %d)", e.Line)
+	}
+	if e.Pid > 0 {
+		msg += fmt.Sprintf(" pid %d", e.Pid)
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *ProcRecordError) Unwrap() error {
+	return e.Err
+}
+
+// procRawRecord es un elemento del arreglo de procesos antes de validarlo.
+// Err es el error de sintaxis si el registro no es JSON válido (solo en la
+// recuperación por líneas).
+type procRawRecord struct {
+	Raw  []byte
+	Line int
+	Err  error
+}
+
+// ParseProcSys valida y convierte el contenido de PROC_SYS. En modo
+// estricto cualquier registro inválido hace fallar la lectura; en modo
+// tolerante se descartan solo los registros inválidos y se retornan en
+// skipped. Un encabezado inválido o una versión no soportada siempre es un
+// error.
+func ParseProcSys(path string, data []byte, tolerant bool) (sys var_const.ProcSys, skipped []ProcRecordError, err error) {
+	h, procs, skipped, err := parseProcFile(path, data, "processes", tolerant)
+	if err != nil {
+		return sys, skipped, err
+	}
+	return var_const.ProcSys{
+		SchemaVersion: h.SchemaVersion,
+		MemTotalKb:    h.MemTotalKb,
+		MemFreeKb:     h.MemFreeKb,
+		MemUsedKb:     h.MemUsedKb,
+		Processes:     procs,
+	}, skipped, nil
+}
+
+// ParseProcCont es el equivalente de ParseProcSys para PROC_CONT.
+func ParseProcCont(path string, data []byte, tolerant bool) (cont var_const.ProcCont, skipped []ProcRecordError, err error) {
+	h, procs, skipped, err := parseProcFile(path, data, "containers", tolerant)
+	if err != nil {
+		return cont, skipped, err
+	}
+	return var_const.ProcCont{
+		SchemaVersion: h.SchemaVersion,
+		MemTotalKb:    h.MemTotalKb,
+		MemFreeKb:     h.MemFreeKb,
+		MemUsedKb:     h.MemUsedKb,
+		Containers:    procs,
+	}, skipped, nil
+}
+
+// procHeader son los campos del documento fuera del arreglo de procesos.
+type procHeader struct {
+	SchemaVersion int
+	MemTotalKb    uint64
+	MemFreeKb     uint64
+	MemUsedKb     uint64
+}
+
+func parseProcFile(path string, data []byte, listKey string, tolerant bool) (procHeader, []var_const.ProcProcess, []ProcRecordError, error) {
+	doc, records, err := splitProcDocument(data, listKey)
+	if err != nil {
+		if !tolerant {
+			return procHeader{}, nil, nil, fmt.Errorf("%s: %v", path, err)
+		}
+		doc, records = recoverProcDocument(data, listKey)
+	}
+
+	h, err := parseProcHeader(doc, tolerant)
+	if err != nil {
+		return procHeader{}, nil, nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	var skipped []ProcRecordError
+	procs := make([]var_const.ProcProcess, 0, len(records))
+	for i, r := range records {
+		var proc var_const.ProcProcess
+		err := r.Err
+		if err == nil {
+			proc, err = parseProcRecord(r.Raw)
+		}
+		if err != nil {
+			e := ProcRecordError{File: path, Index: i, Line: r.Line, Pid: recordPid(r.Raw), Err: err}
+			if !tolerant {
+				return h, nil, nil, &e
+			}
+			skipped = append(skipped, e)
+			continue
+		}
+		procs = append(procs, proc)
+	}
+	return h, procs, skipped, nil
+}
+
+// splitProcDocument recorre el documento con el decodificador de tokens
+// para separar el encabezado de los registros de listKey, guardando la
+// línea de cada registro. Falla ante cualquier error de sintaxis (comas
+// finales, comillas sin escapar...).
+func splitProcDocument(data []byte, listKey string) (map[string]json.RawMessage, []procRawRecord, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	doc := make(map[string]json.RawMessage)
+	var records []procRawRecord
+	hasList := false
+
+	syntaxErr := func(err error) error {
+		var se *json.SyntaxError
+		if errors.As(err, &se) {
+			return fmt.Errorf("línea %d: %v", lineAt(data, se.Offset), err)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return errors.New("documento incompleto")
+		}
+		return err
+	}
+	expect := func(want json.Delim) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return syntaxErr(err)
+		}
+		if d, ok := tok.(json.Delim); !ok || d != want {
+			return fmt.Errorf("línea %d: se esperaba %q", lineAt(data, dec.InputOffset()), want)
+		}
+		return nil
+	}
+
+	if err := expect('{'); err != nil {
+		return nil, nil, err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, syntaxErr(err)
+		}
+		key := tok.(string)
+		if key != listKey {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return nil, nil, syntaxErr(err)
+			}
+			doc[key] = raw
+			continue
+		}
+
+		hasList = true
+		if err := expect('['); err != nil {
+			return nil, nil, err
+		}
+		for dec.More() {
+			off := dec.InputOffset()
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return nil, nil, syntaxErr(err)
+			}
+			records = append(records, procRawRecord{Raw: raw, Line: lineAt(data, skipSeparators(data, off))})
+		}
+		if err := expect(']'); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := expect('}'); err != nil {
+		return nil, nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, nil, fmt.Errorf("línea %d: datos después del documento", lineAt(data, dec.InputOffset()))
+	}
+	if !hasList {
+		return nil, nil, fmt.Errorf("falta el campo %q", listKey)
+	}
+	return doc, records, nil
+}
+
+// recoverProcDocument lee el documento línea por línea, aprovechando que
+// los módulos escriben cada campo del encabezado y cada registro en su
+// propia línea. Se ignoran las comas finales y los registros que no son
+// JSON válido se conservan con su error para informarlos.
+func recoverProcDocument(data []byte, listKey string) (map[string]json.RawMessage, []procRawRecord) {
+	doc := make(map[string]json.RawMessage)
+	var records []procRawRecord
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		line = strings.TrimSpace(strings.TrimSuffix(line, ","))
+
+		switch {
+		case line == "" || line == "{" || line == "}" || line == "]" || strings.HasSuffix(line, "["):
+			// Apertura o cierre del documento o del arreglo
+		case strings.HasPrefix(line, "{"):
+			r := procRawRecord{Raw: []byte(line), Line: n}
+			if !json.Valid(r.Raw) {
+				var v any
+				r.Err = json.Unmarshal(r.Raw, &v)
+			}
+			records = append(records, r)
+		case strings.HasPrefix(line, `"`):
+			var kv map[string]json.RawMessage
+			if err := json.Unmarshal([]byte("{"+line+"}"), &kv); err != nil {
+				continue
+			}
+			for k, v := range kv {
+				if k != listKey {
+					doc[k] = v
+				}
+			}
+		}
+	}
+	return doc, records
+}
+
+// parseProcHeader valida schema_version y la memoria del sistema.
+func parseProcHeader(doc map[string]json.RawMessage, tolerant bool) (procHeader, error) {
+	var h procHeader
+	if raw, ok := doc["schema_version"]; ok {
+		if err := json.Unmarshal(raw, &h.SchemaVersion); err != nil {
+			return h, fmt.Errorf("schema_version: %v", err)
+		}
+		if h.SchemaVersion < 1 {
+			return h, fmt.Errorf("schema_version inválido: %d", h.SchemaVersion)
+		}
+	} else if !tolerant {
+		return h, fmt.Errorf("falta schema_version (módulo del kernel anterior a la versión %d; use proc_parse=tolerant o recompile el módulo)", PROC_SCHEMA_VERSION)
+	}
+	if h.SchemaVersion > PROC_SCHEMA_VERSION {
+		return h, fmt.Errorf("schema_version %d no soportado (máximo %d)", h.SchemaVersion, PROC_SCHEMA_VERSION)
+	}
+
+	for _, f := range []struct {
+		key string
+		dst *uint64
+	}{
+		{"mem_total_kb", &h.MemTotalKb},
+		{"mem_free_kb", &h.MemFreeKb},
+		{"mem_used_kb", &h.MemUsedKb},
+	} {
+		raw, ok := doc[f.key]
+		if !ok {
+			return h, fmt.Errorf("falta el campo %q", f.key)
+		}
+		if err := json.Unmarshal(raw, f.dst); err != nil {
+			return h, fmt.Errorf("%s: %v", f.key, err)
+		}
+	}
+	return h, nil
+}
+
+// procRecord usa punteros para distinguir un campo ausente de su valor
+// cero. Los campos desconocidos se ignoran: agregar campos no cambia la
+// versión del esquema, quitarlos o cambiar su tipo sí.
+type procRecord struct {
+	Pid         *int    `json:"pid"`
+	Name        *string `json:"name"`
+	Cmdline     *string `json:"cmdline"`
+	VszKb       *uint64 `json:"vsz_kb"`
+	RssKb       *uint64 `json:"rss_kb"`
+	MemPct      *string `json:"mem_pct"`
+	ProcJiffies *uint64 `json:"proc_jiffies"`
+	State       *string `json:"state"`
+}
+
+// parseProcRecord valida que el registro tenga todos los campos con el
+// tipo correcto.
+func parseProcRecord(raw []byte) (var_const.ProcProcess, error) {
+	var r procRecord
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return var_const.ProcProcess{}, err
+	}
+
+	var missing []string
+	for _, f := range []struct {
+		name string
+		ok   bool
+	}{
+		{"pid", r.Pid != nil},
+		{"name", r.Name != nil},
+		{"cmdline", r.Cmdline != nil},
+		{"vsz_kb", r.VszKb != nil},
+		{"rss_kb", r.RssKb != nil},
+		{"mem_pct", r.MemPct != nil},
+		{"proc_jiffies", r.ProcJiffies != nil},
+		{"state", r.State != nil},
+	} {
+		if !f.ok {
+			missing = append(missing, f.name)
+		}
+	}
+	if len(missing) > 0 {
+		return var_const.ProcProcess{}, fmt.Errorf("faltan los campos %s", strings.Join(missing, ", "))
+	}
+
+	if *r.Pid <= 0 {
+		return var_const.ProcProcess{}, fmt.Errorf("pid inválido: %d", *r.Pid)
+	}
+	if pct, err := strconv.ParseFloat(*r.MemPct, 64); err != nil || pct < 0 {
+		return var_const.ProcProcess{}, fmt.Errorf("mem_pct inválido: %q", *r.MemPct)
+	}
+	if len(*r.State) != 1 {
+		return var_const.ProcProcess{}, fmt.Errorf("state inválido: %q", *r.State)
+	}
+
+	return var_const.ProcProcess{
+		Pid:         *r.Pid,
+		Name:        *r.Name,
+		Cmdline:     *r.Cmdline,
+		VszKb:       *r.VszKb,
+		RssKb:       *r.RssKb,
+		MemPct:      *r.MemPct,
+		ProcJiffies: *r.ProcJiffies,
+		State:       *r.State,
+	}, nil
+}
+
+// recordPid extrae el pid de un registro, aunque no sea JSON válido, para
+// identificarlo en los errores. Los módulos escriben pid como primer campo.
+func recordPid(raw []byte) int {
+	_, rest, ok := bytes.Cut(raw, []byte(`"pid":`))
+	if !ok {
+		return 0
+	}
+	rest = bytes.TrimLeft(rest, " ")
+	end := 0
+	for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+		end++
+	}
+	pid, _ := strconv.Atoi(string(rest[:end]))
+	return pid
+}
+
+// lineAt retorna la línea (desde 1) del byte off de data.
+func lineAt(data []byte, off int64) int {
+	off = min(max(off, 0), int64(len(data)))
+	return bytes.Count(data[:off], []byte("\n")) + 1
+}
+
+// skipSeparators avanza off sobre los espacios y la coma que preceden a un
+// elemento del arreglo.
+func skipSeparators(data []byte, off int64) int64 {
+	for off < int64(len(data)) {
+		switch data[off] {
+		case ' ', '\t', '\r', '\n', ',':
+			off++
+		default:
+			return off
+		}
+	}
+	return off
+}
@@ -0,0 +1,213 @@
+package functions
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// procDoc arma un documento de PROC_SYS con el formato de sysinfo.c: un
+// campo del encabezado y un registro por línea. version vacío omite
+// schema_version; con trailing el último registro también lleva coma.
+func procDoc(version string, records []string, trailing bool) string {
+	var b strings.Builder
+	b.WriteString("{\n")
+	if version != "" {
+		fmt.Fprintf(&b, "  \"schema_version\": %s,\n", version)
+	}
+	b.WriteString("  \"mem_total_kb\": 8000,\n  \"mem_free_kb\": 2000,\n  \"mem_used_kb\": 6000,\n")
+	b.WriteString("  \"processes\": [\n")
+	for i, r := range records {
+		b.WriteString("    " + r)
+		if i < len(records)-1 || trailing {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("  ]\n}\n")
+	return b.String()
+}
+
+// procRec es un registro válido con el formato de seq_json_process.
+func procRec(pid int, cmdline string) string {
+	return fmt.Sprintf(`{ "pid": %d, "name": "sh", "cmdline": %s, "vsz_kb": 100, "rss_kb": 50, "mem_pct": "0.62", "proc_jiffies": 7, "state": "S" }`, pid, cmdline)
+}
+
+func TestParseProcSysSchemaVersion(t *testing.T) {
+	records := []string{procRec(1, `"init"`)}
+	tests := []struct {
+		name        string
+		version     string
+		strictOk    bool
+		tolerantOk  bool
+		wantVersion int
+	}{
+		{"actual", "1", true, true, 1},
+		{"ausente", "", false, true, 0},
+		{"futura", "2", false, false, 0},
+		{"cero", "0", false, false, 0},
+		{"negativa", "-1", false, false, 0},
+		{"no numérica", `"1"`, false, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(procDoc(tt.version, records, false))
+			for _, mode := range []struct {
+				tolerant bool
+				ok       bool
+			}{{false, tt.strictOk}, {true, tt.tolerantOk}} {
+				sys, _, err := ParseProcSys("sysinfo", data, mode.tolerant)
+				if (err == nil) != mode.ok {
+					t.Fatalf("tolerant=%v: err = %v, se esperaba éxito = %v", mode.tolerant, err, mode.ok)
+				}
+				if err == nil && sys.SchemaVersion != tt.wantVersion {
+					t.Errorf("tolerant=%v: SchemaVersion = %d, se esperaba %d", mode.tolerant, sys.SchemaVersion, tt.wantVersion)
+				}
+			}
+		})
+	}
+}
+
+func TestParseProcSysFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		strictOk bool
+		// En modo estricto falla el registro de wantSkipped[0] (el
+		// documento es JSON válido, no falla la sintaxis)
+		strictRecord bool
+		// Resultado en modo tolerante
+		wantPids    []int
+		wantSkipped []ProcRecordError // solo Index, Line y Pid
+	}{
+		{
+			name:     "válido",
+			data:     procDoc("1", []string{procRec(1, `"init"`), procRec(2, `"sh"`)}, false),
+			strictOk: true,
+			wantPids: []int{1, 2},
+		},
+		{
+			// Salida de los módulos anteriores a schema_version
+			name:     "coma final",
+			data:     procDoc("", []string{procRec(1, `"init"`), procRec(2, `"sh"`)}, true),
+			wantPids: []int{1, 2},
+		},
+		{
+			name: "campo faltante",
+			data: procDoc("1", []string{
+				procRec(1, `"init"`),
+				`{ "pid": 2, "name": "sh", "cmdline": "sh", "vsz_kb": 100, "rss_kb": 50, "mem_pct": "0.62", "state": "S" }`,
+				procRec(3, `"sleep 1"`),
+			}, false),
+			strictRecord: true,
+			wantPids:     []int{1, 3},
+			wantSkipped:  []ProcRecordError{{Index: 1, Line: 8, Pid: 2}},
+		},
+		{
+			// Comillas sin escapar: el documento no es JSON y se recupera por líneas
+			name: "cmdline sin escapar",
+			data: procDoc("", []string{
+				procRec(1, `"init"`),
+				procRec(2, `"sh -c "echo hola""`),
+				procRec(3, `"sleep 1"`),
+			}, true),
+			wantPids:    []int{1, 3},
+			wantSkipped: []ProcRecordError{{Index: 1, Line: 7, Pid: 2}},
+		},
+		{
+			name: "tipo incorrecto",
+			data: procDoc("1", []string{
+				`{ "pid": "1", "name": "init", "cmdline": "init", "vsz_kb": 100, "rss_kb": 50, "mem_pct": "0.62", "proc_jiffies": 7, "state": "S" }`,
+				procRec(2, `"sh"`),
+			}, false),
+			strictRecord: true,
+			wantPids:     []int{2},
+			wantSkipped:  []ProcRecordError{{Index: 0, Line: 7}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseProcSys("sysinfo", []byte(tt.data), false)
+			if (err == nil) != tt.strictOk {
+				t.Errorf("estricto: err = %v, se esperaba éxito = %v", err, tt.strictOk)
+			}
+			if tt.strictRecord {
+				var re *ProcRecordError
+				if !errors.As(err, &re) {
+					t.Errorf("estricto: err = %v, se esperaba un ProcRecordError", err)
+				} else if re.Index != tt.wantSkipped[0].Index || re.Line != tt.wantSkipped[0].Line {
+					t.Errorf("estricto: registro %d línea %d, se esperaba %d línea %d", re.Index, re.Line, tt.wantSkipped[0].Index, tt.wantSkipped[0].Line)
+				}
+			}
+
+			sys, skipped, err := ParseProcSys("sysinfo", []byte(tt.data), true)
+			if err != nil {
+				t.Fatalf("tolerante: %v", err)
+			}
+			var pids []int
+			for _, p := range sys.Processes {
+				pids = append(pids, p.Pid)
+			}
+			if fmt.Sprint(pids) != fmt.Sprint(tt.wantPids) {
+				t.Errorf("tolerante: pids = %v, se esperaba %v", pids, tt.wantPids)
+			}
+			if len(skipped) != len(tt.wantSkipped) {
+				t.Fatalf("tolerante: %d registros descartados, se esperaban %d: %v", len(skipped), len(tt.wantSkipped), skipped)
+			}
+			for i, want := range tt.wantSkipped {
+				got := skipped[i]
+				if got.Index != want.Index || got.Line != want.Line || got.Pid != want.Pid {
+					t.Errorf("tolerante: descartado (índice %d, línea %d, pid %d), se esperaba (índice %d, línea %d, pid %d)",
+						got.Index, got.Line, got.Pid, want.Index, want.Line, want.Pid)
+				}
+				if got.File != "sysinfo" || got.Err == nil {
+					t.Errorf("tolerante: descartado sin archivo o error: %+v", got)
+				}
+			}
+		})
+	}
+}
+
+func TestParseProcSysEscapedStrings(t *testing.T) {
+	// Cadenas escapadas como seq_json_string: comillas, barras invertidas,
+	// tabuladores y controles en \u00XX
+	tests := []struct {
+		cmdline string // literal JSON
+		want    string
+	}{
+		{`"sh -c \"echo hola\""`, `sh -c "echo hola"`},
+		{`"C:\\tmp\\app.exe"`, `C:\tmp\app.exe`},
+		{`"printf \"\\\\\""`, `printf "\\"`},
+		{`"a\tb\nc"`, "a\tb\nc"},
+		{`"esc \u001b[0m \u007f"`, "esc \x1b[0m \x7f"},
+		{`""`, ""},
+	}
+	for _, tt := range tests {
+		data := []byte(procDoc("1", []string{procRec(1, tt.cmdline)}, false))
+		sys, _, err := ParseProcSys("sysinfo", data, false)
+		if err != nil {
+			t.Errorf("cmdline %s: %v", tt.cmdline, err)
+			continue
+		}
+		if got := sys.Processes[0].Cmdline; got != tt.want {
+			t.Errorf("cmdline %s = %q, se esperaba %q", tt.cmdline, got, tt.want)
+		}
+	}
+}
+
+func TestParseProcContListKey(t *testing.T) {
+	// PROC_CONT usa "containers" como arreglo; "processes" no es válido
+	data := []byte(procDoc("1", []string{procRec(1, `"init"`)}, false))
+	if _, _, err := ParseProcCont("continfo", data, false); err == nil {
+		t.Error("se esperaba un error sin el campo containers")
+	}
+	data = []byte(strings.Replace(string(data), `"processes"`, `"containers"`, 1))
+	cont, _, err := ParseProcCont("continfo", data, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cont.Containers) != 1 || cont.MemTotalKb != 8000 {
+		t.Errorf("ParseProcCont = %+v", cont)
+	}
+}
@@ -0,0 +1,9 @@
+package functions
+
+import (
+	"so1-daemon/cruntime"
+)
+
+// Runtime es el motor de contenedores usado por el paquete (Docker,
+// containerd o Podman). Se inicializa en main según la configuración.
+var Runtime cruntime.ContainerRuntime = cruntime.NewDocker("/var/run/docker.sock")
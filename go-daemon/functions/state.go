@@ -0,0 +1,35 @@
+package functions
+
+import (
+	"so1-daemon/var_const"
+	"sync"
+)
+
+// Último estado medido por ProcessOnce, consultado por la API HTTP.
+var (
+	snapshot     var_const.Snapshot
+	snapshotLock sync.RWMutex
+)
+
+// LastSnapshot retorna una copia del resultado del último ciclo.
+func LastSnapshot() var_const.Snapshot {
+	snapshotLock.RLock()
+	defer snapshotLock.RUnlock()
+
+	s := snapshot
+	s.Containers = append([]var_const.ContainerSample(nil), snapshot.Containers...)
+	s.System.Cores = append([]var_const.CoreUsage(nil), snapshot.System.Cores...)
+	return s
+}
+
+func setSystemSnapshot(sys var_const.SystemStatus) {
+	snapshotLock.Lock()
+	defer snapshotLock.Unlock()
+	snapshot.System = sys
+}
+
+func setContainersSnapshot(containers []var_const.ContainerSample) {
+	snapshotLock.Lock()
+	defer snapshotLock.Unlock()
+	snapshot.Containers = containers
+}
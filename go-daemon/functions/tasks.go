@@ -0,0 +1,124 @@
+package functions
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// TaskUsage es el consumo sumado de todas las tareas de un contenedor.
+// CpuTicks es utime + stime acumulado de las tareas vivas (USER_HZ); baja
+// cuando una tarea termina, por lo que solo se usa si no se puede leer el
+// contador del cgroup.
+type TaskUsage struct {
+	Tasks    int
+	RssKb    uint64
+	VszKb    uint64
+	CpuTicks uint64
+}
+
+// ContainerTasks retorna los PIDs de todas las tareas del contenedor. Se
+// leen los cgroup.procs de su cgroup y de los cgroups hijos; si no se
+// pueden leer se buscan los procesos que comparten el namespace de PID de
+// pid. Si pid está en el namespace del host solo se retorna pid.
+func ContainerTasks(paths CgroupPaths, pid int) ([]int, error) {
+	if dir := tasksDir(paths); dir != "" {
+		pids, err := readCgroupProcs(dir)
+		if err == nil && len(pids) > 0 {
+			return pids, nil
+		}
+	}
+	return pidNamespaceTasks(pid)
+}
+
+// tasksDir elige el directorio de cgroup.procs. En modo híbrido el cgroup
+// unificado puede ser la raíz (driver cgroupfs), así que se prefieren los
+// controladores de v1.
+func tasksDir(paths CgroupPaths) string {
+	for _, ctrl := range []string{"memory", "pids", "cpuacct"} {
+		if dir, ok := paths.V1[ctrl]; ok {
+			return dir
+		}
+	}
+	return paths.V2
+}
+
+// readCgroupProcs recorre dir y sus subdirectorios (contenedores con
+// systemd o cgroups anidados) y une sus cgroup.procs.
+func readCgroupProcs(dir string) ([]int, error) {
+	var pids []int
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Un cgroup hijo puede eliminarse durante el recorrido
+			if path != dir && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		f, err := os.Open(filepath.Join(path, "cgroup.procs"))
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if pid, err := strconv.Atoi(scanner.Text()); err == nil {
+				pids = append(pids, pid)
+			}
+		}
+		return scanner.Err()
+	})
+	return pids, err
+}
+
+// pidNamespaceTasks retorna los procesos de /proc cuyo namespace de PID es
+// el de pid.
+func pidNamespaceTasks(pid int) ([]int, error) {
+	ns, err := os.Readlink(filepath.Join("/proc", strconv.Itoa(pid), "ns", "pid"))
+	if err != nil {
+		return nil, err
+	}
+	if self, err := os.Readlink("/proc/self/ns/pid"); err == nil && self == ns {
+		return []int{pid}, nil
+	}
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, e := range entries {
+		p, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		if link, err := os.Readlink(filepath.Join("/proc", e.Name(), "ns", "pid")); err == nil && link == ns {
+			pids = append(pids, p)
+		}
+	}
+	return pids, nil
+}
+
+// SumTasks suma la memoria y el tiempo de CPU de las tareas según
+// /proc/<pid>/stat. Las tareas que terminan durante la lectura se omiten.
+func SumTasks(pids []int) TaskUsage {
+	pageKb := uint64(os.Getpagesize() / 1024)
+	var u TaskUsage
+	for _, pid := range pids {
+		st, err := ReadProcStat(pid)
+		if err != nil {
+			continue
+		}
+		u.Tasks++
+		u.RssKb += st.RssPages * pageKb
+		u.VszKb += st.Vsize / 1024
+		u.CpuTicks += st.Utime + st.Stime
+	}
+	return u
+}
@@ -2,7 +2,10 @@ module so1-daemon
 
 go 1.24.0
 
-require modernc.org/sqlite v1.40.1
+require (
+	github.com/lib/pq v1.10.9
+	modernc.org/sqlite v1.40.1
+)
 
 require (
 	github.com/dustin/go-humanize v1.0.1 // indirect
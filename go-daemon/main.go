@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"so1-daemon/api"
+	"so1-daemon/config"
+	"so1-daemon/cruntime"
 	"so1-daemon/database"
 	"so1-daemon/functions"
 	"so1-daemon/utils"
-	"so1-daemon/var_const"
 	"syscall"
 	"time"
 )
@@ -15,7 +20,38 @@ import (
 func main() {
 	// Logs Basicos
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
+
+	// Configuración: archivo JSON + variables SO1_* + flags
+	configPath := flag.String("config", os.Getenv("SO1_CONFIG"), "archivo de configuración JSON (env SO1_CONFIG)")
+	overrides := config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath, overrides.Values())
+	if err != nil {
+		log.Fatalf("Error de configuración: %v", err)
+	}
+	config.Set(cfg)
+
+	// SIGHUP recarga la configuración sin reiniciar el daemon. Se registra
+	// antes de iniciar el resto: por defecto SIGHUP termina el proceso y
+	// una recarga durante el arranque lo detendría. La señal queda en el
+	// canal y se atiende al entrar al bucle.
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+
+	// Subcomandos: "migrate [status|up]" opera sobre la base y termina
+	switch flag.Arg(0) {
+	case "":
+	case "migrate":
+		os.Exit(runMigrate(flag.Args()[1:]))
+	default:
+		log.Fatalf("Subcomando desconocido: %q (disponible: migrate)", flag.Arg(0))
+	}
+
 	log.Println("Iniciando Daemon...")
+	if cfg.DryRun {
+		log.Println("Modo observación (dry-run): no se eliminará ningún contenedor.")
+	}
 
 	//Inicializar Grafana
 	if err := utils.StartGrafana(); err != nil {
@@ -29,9 +65,25 @@ func main() {
 		log.Fatalf("Error de Incio DB: %v", err)
 	}
 
-	log.Println("Base de datos inicializada en", var_const.DB_PATH)
-	if _, err := os.Stat(utils.ABSPATH("./data")); os.IsNotExist(err) {
-		_ = os.MkdirAll("./data", 0755)
+	log.Println("Almacenamiento de métricas inicializado:", database.Store.Name())
+
+	// Runtime de contenedores (docker, containerd o podman)
+	rt, err := cruntime.New(cfg)
+	if err != nil {
+		log.Fatalf("Error de runtime: %v", err)
+	}
+	functions.Runtime = rt
+	log.Println("Runtime de contenedores:", rt.Name())
+
+	// Rollups, retención y VACUUM de la base en segundo plano
+	maintenanceStop := make(chan struct{})
+	database.StartMaintenance(maintenanceStop)
+
+	// API HTTP con el estado del último ciclo
+	var srv *http.Server
+	if cfg.HTTPListen != "" {
+		srv = api.Start(cfg.HTTPListen, api.NewMux())
+		log.Println("API HTTP escuchando en", cfg.HTTPListen)
 	}
 
 	// Generar los 10 contenedores
@@ -49,7 +101,7 @@ func main() {
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 
 	// Bucle
-	ticker := time.NewTicker(20 * time.Second)
+	ticker := time.NewTicker(cfg.Interval.Duration)
 	defer ticker.Stop()
 
 	// 1. PRIMERA MEDICIÓN: Solo guarda los datos base (CPU = 0.0)
@@ -65,6 +117,25 @@ loop:
 			if err := functions.ProcessOnce(); err != nil {
 				log.Printf("Error en ProcessOnce(): %v", err)
 			}
+		case <-reload:
+			log.Println("SIGHUP recibido, recargando configuración...")
+			next, warnings, err := config.Reload(*configPath, overrides.Values())
+			if err != nil {
+				log.Printf("Advertencia: no se aplicó la nueva configuración: %v", err)
+				continue
+			}
+			for _, w := range warnings {
+				log.Printf("Advertencia: %s", w)
+			}
+			if next.Interval.Duration != cfg.Interval.Duration {
+				ticker.Reset(next.Interval.Duration)
+				log.Printf("Intervalo actualizado de %s a %s", cfg.Interval.Duration, next.Interval.Duration)
+			}
+			if next.DryRun != cfg.DryRun {
+				log.Printf("Modo observación (dry-run): %v", next.DryRun)
+			}
+			cfg = next
+			log.Println("Configuración recargada.")
 		case <-stop:
 			log.Println("Señal recibida para detener, limpiando...")
 			break loop
@@ -72,6 +143,15 @@ loop:
 	}
 
 	// cleanup
+	close(maintenanceStop)
+	if srv != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("Advertencia al detener la API HTTP: %v", err)
+		}
+		cancel()
+	}
+
 	if err := utils.RemoveCron(); err != nil {
 		log.Printf("Advertencia al eliminar cron: %v", err)
 	}
@@ -79,6 +159,9 @@ loop:
 	if err := utils.StopContainer(); err != nil {
 		log.Printf("Advertencia al eliminar contenedores: %v", err)
 	}
+	if err := database.CloseDB(); err != nil {
+		log.Printf("Advertencia al cerrar la base de datos: %v", err)
+	}
 	log.Println("Salida de Daemon.")
 
 }
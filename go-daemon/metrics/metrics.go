@@ -0,0 +1,322 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"so1-daemon/var_const"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NAMESPACE es el prefijo de todas las métricas exportadas.
+const NAMESPACE = "so1"
+
+// CONTENT_TYPE es el tipo de contenido del formato de texto de Prometheus.
+const CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+
+// TICK_BUCKETS son los límites (segundos) del histograma de duración de
+// cada ciclo de ProcessOnce.
+var TICK_BUCKETS = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20}
+
+// Contadores acumulados desde el arranque del daemon. Los gauges no se
+// guardan aquí: se generan en cada consulta a partir del último Snapshot.
+var (
+	lock sync.Mutex
+
+	// clave: class, reason
+	deletions = make(map[[2]string]uint64)
+	// clave: class, action, dry_run
+	actions = make(map[[3]string]uint64)
+	// clave: archivo /proc
+	procSkipped = make(map[string]uint64)
+
+	tickCounts = make([]uint64, len(TICK_BUCKETS))
+	tickSum    float64
+	tickTotal  uint64
+	tickErrors uint64
+)
+
+// IncDeletion cuenta una eliminación real. reason es la métrica que la
+// disparó (ver rules.ReasonMetrics).
+func IncDeletion(class, reason string) {
+	lock.Lock()
+	defer lock.Unlock()
+	deletions[[2]string{class, reason}]++
+}
+
+// IncAction cuenta un escalón aplicado (o simulado en dry-run).
+func IncAction(class, action string, dryRun bool) {
+	lock.Lock()
+	defer lock.Unlock()
+	actions[[3]string{class, action, strconv.FormatBool(dryRun)}]++
+}
+
+// AddProcSkipped cuenta los registros de un archivo /proc descartados por
+// no cumplir el formato (modo tolerante).
+func AddProcSkipped(file string, n int) {
+	lock.Lock()
+	defer lock.Unlock()
+	procSkipped[file] += uint64(n)
+}
+
+// ObserveTick registra la duración de un ciclo y si terminó con error.
+func ObserveTick(d time.Duration, failed bool) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	secs := d.Seconds()
+	for i, le := range TICK_BUCKETS {
+		if secs <= le {
+			tickCounts[i]++
+		}
+	}
+	tickSum += secs
+	tickTotal++
+	if failed {
+		tickErrors++
+	}
+}
+
+// WriteText escribe todas las métricas en el formato de texto de Prometheus.
+func WriteText(w io.Writer, snap var_const.Snapshot) error {
+	b := &strings.Builder{}
+
+	// Contenedores del último ciclo
+	containers := uniqueContainers(snap.Containers)
+	header(b, "container_cpu_percent", "gauge", "Uso de CPU (%) del contenedor en el último ciclo.")
+	for _, c := range containers {
+		sample(b, "container_cpu_percent", containerLabels(c), c.CpuPct)
+	}
+	header(b, "container_cpu_host_percent", "gauge", "Uso de CPU (%) del contenedor respecto de todos los núcleos del host.")
+	for _, c := range containers {
+		sample(b, "container_cpu_host_percent", containerLabels(c), c.CpuHostPct)
+	}
+	header(b, "container_cpu_quota_percent", "gauge", "Uso de CPU (%) del contenedor respecto de su cuota de CPU (o del host si no tiene cuota).")
+	for _, c := range containers {
+		sample(b, "container_cpu_quota_percent", containerLabels(c), c.CpuQuotaPct)
+	}
+	header(b, "container_memory_percent", "gauge", "Uso de memoria (%) del contenedor en el último ciclo.")
+	for _, c := range containers {
+		sample(b, "container_memory_percent", containerLabels(c), c.MemPct)
+	}
+
+	// Recursos del cgroup (solo contenedores cuyo cgroup se pudo leer)
+	header(b, "container_memory_bytes", "gauge", "Memoria del cgroup del contenedor sin la caché inactiva.")
+	for _, c := range containers {
+		if c.Cgroup != nil {
+			sample(b, "container_memory_bytes", containerLabels(c), float64(c.Cgroup.MemoryWorkingSet))
+		}
+	}
+	header(b, "container_memory_limit_bytes", "gauge", "Límite de memoria del cgroup del contenedor (sin serie si no tiene límite).")
+	for _, c := range containers {
+		if c.Cgroup != nil && c.Cgroup.MemoryMax > 0 {
+			sample(b, "container_memory_limit_bytes", containerLabels(c), float64(c.Cgroup.MemoryMax))
+		}
+	}
+	header(b, "container_cpu_limit_cpus", "gauge", "Cuota de CPU del cgroup del contenedor en CPUs (sin serie si no tiene cuota).")
+	for _, c := range containers {
+		if c.Cgroup != nil && c.Cgroup.CpuLimit > 0 {
+			sample(b, "container_cpu_limit_cpus", containerLabels(c), c.Cgroup.CpuLimit)
+		}
+	}
+	header(b, "container_pids", "gauge", "Tareas en el cgroup del contenedor.")
+	for _, c := range containers {
+		if c.Cgroup != nil {
+			sample(b, "container_pids", containerLabels(c), float64(c.Cgroup.PidsCurrent))
+		}
+	}
+	header(b, "container_memory_pressure_some_avg10", "gauge", "PSI de memoria (some, avg10) del cgroup del contenedor (solo cgroups v2).")
+	for _, c := range containers {
+		if c.Cgroup != nil && c.Cgroup.MemoryPressure != nil {
+			sample(b, "container_memory_pressure_some_avg10", containerLabels(c), c.Cgroup.MemoryPressure.Some.Avg10)
+		}
+	}
+
+	// Sistema
+	header(b, "system_memory_total_bytes", "gauge", "Memoria total del sistema.")
+	sample(b, "system_memory_total_bytes", nil, float64(snap.System.MemTotalKb)*1024)
+	header(b, "system_memory_free_bytes", "gauge", "Memoria libre del sistema.")
+	sample(b, "system_memory_free_bytes", nil, float64(snap.System.MemFreeKb)*1024)
+	header(b, "system_memory_used_bytes", "gauge", "Memoria usada del sistema.")
+	sample(b, "system_memory_used_bytes", nil, float64(snap.System.MemUsedKb)*1024)
+	if c := snap.System.Cpu; c != nil {
+		header(b, "host_cpu_percent", "gauge", "Reparto del tiempo de CPU del host (%) en el último ciclo.")
+		for _, m := range []struct {
+			mode string
+			v    float64
+		}{
+			{"busy", c.BusyPct},
+			{"user", c.UserPct},
+			{"system", c.SystemPct},
+			{"idle", c.IdlePct},
+			{"iowait", c.IowaitPct},
+			{"steal", c.StealPct},
+		} {
+			sample(b, "host_cpu_percent", []label{{"mode", m.mode}}, m.v)
+		}
+	}
+	header(b, "cpu_core_busy_percent", "gauge", "Uso (%) de cada núcleo en el último ciclo.")
+	for _, c := range snap.System.Cores {
+		sample(b, "cpu_core_busy_percent", []label{{"cpu", strconv.Itoa(c.Cpu)}}, c.BusyPct)
+	}
+	header(b, "processes", "gauge", "Cantidad de procesos reportados por el módulo del kernel.")
+	sample(b, "processes", nil, float64(snap.System.Processes))
+	header(b, "processes_by_state", "gauge", "Procesos por estado del kernel en el último ciclo.")
+	st := snap.System.States
+	for _, s := range []struct {
+		state string
+		n     int
+	}{
+		{"running", st.Running},
+		{"sleeping", st.Sleeping},
+		{"disk_sleep", st.DiskSleep},
+		{"stopped", st.Stopped},
+		{"zombie", st.Zombie},
+		{"idle", st.Idle},
+		{"other", st.Other},
+	} {
+		sample(b, "processes_by_state", []label{{"state", s.state}}, float64(s.n))
+	}
+
+	if !snap.Timestamp.IsZero() {
+		header(b, "last_tick_timestamp_seconds", "gauge", "Momento en que terminó el último ciclo.")
+		sample(b, "last_tick_timestamp_seconds", nil, float64(snap.Timestamp.UnixNano())/1e9)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	header(b, "deletions_total", "counter", "Contenedores eliminados por clase y métrica que disparó la eliminación.")
+	for _, k := range sortedKeys2(deletions) {
+		sample(b, "deletions_total", []label{{"class", k[0]}, {"reason", k[1]}}, float64(deletions[k]))
+	}
+
+	header(b, "actions_total", "counter", "Escalones de la escalera de sanciones aplicados.")
+	for _, k := range sortedKeys3(actions) {
+		sample(b, "actions_total", []label{{"class", k[0]}, {"action", k[1]}, {"dry_run", k[2]}}, float64(actions[k]))
+	}
+
+	header(b, "proc_records_skipped_total", "counter", "Registros de los archivos /proc de los módulos del kernel descartados por no cumplir el formato.")
+	for _, k := range sortedKeys(procSkipped) {
+		sample(b, "proc_records_skipped_total", []label{{"file", k}}, float64(procSkipped[k]))
+	}
+
+	header(b, "tick_errors_total", "counter", "Ciclos de ProcessOnce que terminaron con error.")
+	sample(b, "tick_errors_total", nil, float64(tickErrors))
+
+	header(b, "tick_duration_seconds", "histogram", "Duración de cada ciclo de ProcessOnce.")
+	for i, le := range TICK_BUCKETS {
+		sample(b, "tick_duration_seconds_bucket", []label{{"le", formatFloat(le)}}, float64(tickCounts[i]))
+	}
+	sample(b, "tick_duration_seconds_bucket", []label{{"le", "+Inf"}}, float64(tickTotal))
+	sample(b, "tick_duration_seconds_sum", nil, tickSum)
+	sample(b, "tick_duration_seconds_count", nil, float64(tickTotal))
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type label struct {
+	name, value string
+}
+
+// uniqueContainers descarta los procesos que no son contenedores y los
+// IDs repetidos (un contenedor puede aparecer por su PID y por su shim),
+// ya que Prometheus rechaza series duplicadas.
+func uniqueContainers(list []var_const.ContainerSample) []var_const.ContainerSample {
+	seen := make(map[string]bool, len(list))
+	var out []var_const.ContainerSample
+	for _, c := range list {
+		if c.ContainerID == "" || seen[c.ContainerID] {
+			continue
+		}
+		seen[c.ContainerID] = true
+		out = append(out, c)
+	}
+	return out
+}
+
+func containerLabels(c var_const.ContainerSample) []label {
+	return []label{
+		{"container_id", c.ContainerID},
+		{"image", c.Image},
+		{"class", c.Class},
+	}
+}
+
+func header(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s_%s %s\n", NAMESPACE, name, help)
+	fmt.Fprintf(b, "# TYPE %s_%s %s\n", NAMESPACE, name, kind)
+}
+
+func sample(b *strings.Builder, name string, labels []label, v float64) {
+	b.WriteString(NAMESPACE + "_" + name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, "%s=\"%s\"", l.name, escapeLabel(l.value))
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(v))
+	b.WriteByte('\n')
+}
+
+// escapeLabel aplica el escape del formato de texto: \\, \" y \n.
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys2(m map[[2]string]uint64) [][2]string {
+	keys := make([][2]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	return keys
+}
+
+func sortedKeys3(m map[[3]string]uint64) [][3]string {
+	keys := make([][3]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.Join(keys[i][:], "\x00") < strings.Join(keys[j][:], "\x00")
+	})
+	return keys
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
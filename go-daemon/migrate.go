@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"so1-daemon/config"
+	"so1-daemon/database"
+	"text/tabwriter"
+)
+
+// runMigrate implementa el subcomando "migrate":
+//
+//	mydaemon [flags] migrate [status]  muestra las migraciones y su estado
+//	mydaemon [flags] migrate up        aplica las migraciones pendientes
+//
+// Usa la misma configuración (storage, db_path, postgres_dsn) que el daemon. Retorna el código
+// de salida del proceso.
+func runMigrate(args []string) int {
+	action := "status"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	if len(args) > 1 || (action != "status" && action != "up") {
+		fmt.Fprintln(os.Stderr, "uso: mydaemon [flags] migrate [status|up]")
+		return 2
+	}
+
+	if err := database.OpenStore(config.Get()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error de Incio DB: %v\n", err)
+		return 1
+	}
+	defer database.CloseDB()
+
+	migrator, ok := database.Store.(database.Migrator)
+	if !ok {
+		fmt.Printf("El almacenamiento %s no usa migraciones.\n", database.Store.Name())
+		return 0
+	}
+
+	if action == "up" {
+		applied, err := migrator.ApplyMigrations()
+		for _, m := range applied {
+			fmt.Printf("aplicada %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("No hay migraciones pendientes.")
+		}
+		return 0
+	}
+
+	status, err := migrator.MigrationsStatus()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSIÓN\tNOMBRE\tESTADO\tAPLICADA")
+	pending := 0
+	for _, s := range status {
+		state, at := "pendiente", "-"
+		if s.Applied {
+			state, at = "aplicada", s.AppliedAt.Format("2006-01-02 15:04:05")
+		} else {
+			pending++
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, at)
+	}
+	w.Flush()
+	fmt.Printf("%d migraciones pendientes.\n", pending)
+	return 0
+}
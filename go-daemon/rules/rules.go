@@ -0,0 +1,401 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"so1-daemon/var_const"
+	"strings"
+)
+
+// Métricas que una clase puede evaluar
+const (
+	METRIC_CPU = "cpu"
+	METRIC_MEM = "mem"
+)
+
+// Escalas del %CPU contra las que se expresa cpu_threshold
+const (
+	CPU_VIEW_RAW   = "raw"   // estilo docker stats: 100% por núcleo, puede superar 100
+	CPU_VIEW_HOST  = "host"  // respecto de todos los núcleos del host (0-100)
+	CPU_VIEW_QUOTA = "quota" // respecto de la cuota de CPU del contenedor (cpu.max)
+)
+
+// CpuValue retorna el %CPU en la escala view.
+func CpuValue(v var_const.CpuViews, view string) float64 {
+	switch view {
+	case CPU_VIEW_HOST:
+		return v.Host
+	case CPU_VIEW_QUOTA:
+		return v.Quota
+	default:
+		return v.Raw
+	}
+}
+
+// Acciones de la escalera de sanciones, de menor a mayor severidad
+const (
+	ACTION_THROTTLE = "throttle" // limita CPU / memoria del contenedor
+	ACTION_PAUSE    = "pause"
+	ACTION_STOP     = "stop"
+	ACTION_REMOVE   = "remove"
+)
+
+// Step es un escalón de la escalera de sanciones de una clase. Se aplica
+// cuando el contenedor acumula After ciclos consecutivos en violación.
+type Step struct {
+	Action   string  `json:"action"`
+	After    int     `json:"after"`
+	CPUs     float64 `json:"cpus,omitempty"`      // throttle: CPUs permitidas (ej. 0.5)
+	MemoryMB int64   `json:"memory_mb,omitempty"` // throttle: límite de memoria
+}
+
+// Match describe un criterio de clasificación. Todos los campos indicados
+// deben cumplirse (AND); dentro de una lista de globs basta con uno (OR).
+// Las comparaciones de imagen y nombre no distinguen mayúsculas y en los
+// globs "*" también abarca "/" (registro y namespace de la imagen).
+type Match struct {
+	Images     []string          `json:"images,omitempty"`      // globs sobre la imagen (ej. "*high_cpu_img*")
+	ImageRegex string            `json:"image_regex,omitempty"` // expresión regular sobre la imagen
+	Names      []string          `json:"names,omitempty"`       // globs sobre el nombre del contenedor
+	NameRegex  string            `json:"name_regex,omitempty"`  // expresión regular sobre el nombre
+	Labels     map[string]string `json:"labels,omitempty"`      // etiquetas requeridas; "*" acepta cualquier valor
+}
+
+// Class define un tipo de carga de trabajo y la política que se le aplica.
+type Class struct {
+	// Nombre de la clase (low, high-cpu, high-mem, protected o uno propio)
+	Name string `json:"name"`
+	// El contenedor pertenece a la clase si cumple alguno de los criterios (OR)
+	Match []Match `json:"match"`
+	// Las clases protegidas nunca se eliminan (ej. grafana)
+	Protected bool `json:"protected,omitempty"`
+	// Métricas que pueden disparar una acción: "cpu", "mem"
+	Evaluate []string `json:"evaluate,omitempty"`
+	// Umbrales propios (%); 0 usa los umbrales globales de la configuración
+	CPUThreshold float64 `json:"cpu_threshold,omitempty"`
+	MemThreshold float64 `json:"mem_threshold,omitempty"`
+	// Escala de cpu_threshold: raw, host o quota; vacío usa la global
+	CPUView string `json:"cpu_view,omitempty"`
+	// Mínimo de contenedores del grupo que deben quedar en ejecución
+	MinCount int `json:"min_count"`
+	// Grupo para el conteo de mínimos; por defecto el nombre de la clase.
+	// high-cpu y high-mem comparten el grupo "high".
+	Group string `json:"group,omitempty"`
+	// Escalera de sanciones; si se omite el contenedor se elimina en la
+	// primera violación (comportamiento histórico)
+	Escalation []Step `json:"escalation,omitempty"`
+}
+
+// Ladder retorna la escalera de sanciones efectiva de la clase.
+func (c *Class) Ladder() []Step {
+	if len(c.Escalation) == 0 {
+		return []Step{{Action: ACTION_REMOVE, After: 1}}
+	}
+	return c.Escalation
+}
+
+// GroupName retorna el grupo usado para contar el mínimo de la clase.
+func (c *Class) GroupName() string {
+	if c.Group != "" {
+		return c.Group
+	}
+	return c.Name
+}
+
+// Thresholds retorna los umbrales efectivos de la clase.
+func (c *Class) Thresholds(globalCPU, globalMem float64) (cpu, mem float64) {
+	cpu, mem = globalCPU, globalMem
+	if c.CPUThreshold > 0 {
+		cpu = c.CPUThreshold
+	}
+	if c.MemThreshold > 0 {
+		mem = c.MemThreshold
+	}
+	return cpu, mem
+}
+
+// View retorna la escala efectiva del umbral de CPU de la clase.
+func (c *Class) View(globalView string) string {
+	if c.CPUView != "" {
+		return c.CPUView
+	}
+	return globalView
+}
+
+func (c *Class) evaluates(metric string) bool {
+	for _, m := range c.Evaluate {
+		if m == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// Violation indica si los valores medidos superan los umbrales de la clase
+// y retorna la razón. El %CPU se compara en la escala de la clase (ver
+// View); la razón la indica si no es raw. Las clases protegidas nunca se
+// consideran en violación.
+func (c *Class) Violation(cpu var_const.CpuViews, memPct, globalCPU, globalMem float64, globalView string) (bool, string) {
+	if c.Protected {
+		return false, ""
+	}
+	cpuT, memT := c.Thresholds(globalCPU, globalMem)
+	view := c.View(globalView)
+
+	var reasons []string
+	if cpuPct := CpuValue(cpu, view); c.evaluates(METRIC_CPU) && cpuPct > cpuT {
+		r := fmt.Sprintf("cpu %.2f > %.2f", cpuPct, cpuT)
+		if view != CPU_VIEW_RAW {
+			r += " (" + view + ")"
+		}
+		reasons = append(reasons, r)
+	}
+	if c.evaluates(METRIC_MEM) && memPct > memT {
+		reasons = append(reasons, fmt.Sprintf("mem %.2f > %.2f", memPct, memT))
+	}
+	if len(reasons) == 0 {
+		return false, ""
+	}
+	return true, fmt.Sprintf("[%s] %s", c.Name, strings.Join(reasons, ", "))
+}
+
+// ReasonMetrics resume una razón generada por Violation en las métricas
+// que la dispararon: "cpu", "mem", "cpu_mem" o "unknown". Se usa como
+// etiqueta de baja cardinalidad (ej. en Prometheus).
+func ReasonMetrics(reason string) string {
+	if i := strings.Index(reason, "] "); i >= 0 {
+		reason = reason[i+2:]
+	}
+	cpu := strings.HasPrefix(reason, METRIC_CPU+" ")
+	mem := strings.HasPrefix(reason, METRIC_MEM+" ") || strings.Contains(reason, ", "+METRIC_MEM+" ")
+	switch {
+	case cpu && mem:
+		return "cpu_mem"
+	case cpu:
+		return METRIC_CPU
+	case mem:
+		return METRIC_MEM
+	default:
+		return "unknown"
+	}
+}
+
+// compiledMatch es un Match con sus expresiones regulares compiladas.
+type compiledMatch struct {
+	Match
+	imageRe *regexp.Regexp
+	nameRe  *regexp.Regexp
+}
+
+type compiledClass struct {
+	class   Class
+	matches []compiledMatch
+}
+
+// Engine clasifica contenedores según una lista ordenada de clases; la
+// primera clase que coincide gana.
+type Engine struct {
+	classes []compiledClass
+}
+
+// Compile valida las clases y prepara el motor de reglas. Los errores
+// indican la clase y el criterio inválido.
+func Compile(classes []Class) (*Engine, error) {
+	var errs []error
+	seen := make(map[string]bool)
+	e := &Engine{}
+
+	for i, c := range classes {
+		label := fmt.Sprintf("classes[%d] (%s)", i, c.Name)
+		if c.Name == "" {
+			errs = append(errs, fmt.Errorf("classes[%d]: name no puede estar vacío", i))
+		} else if seen[c.Name] {
+			errs = append(errs, fmt.Errorf("%s: nombre de clase repetido", label))
+		}
+		seen[c.Name] = true
+
+		if len(c.Match) == 0 {
+			errs = append(errs, fmt.Errorf("%s: match debe tener al menos un criterio", label))
+		}
+		for _, m := range c.Evaluate {
+			if m != METRIC_CPU && m != METRIC_MEM {
+				errs = append(errs, fmt.Errorf("%s: evaluate solo acepta \"cpu\" o \"mem\" (actual %q)", label, m))
+			}
+		}
+		switch c.CPUView {
+		case "", CPU_VIEW_RAW, CPU_VIEW_HOST, CPU_VIEW_QUOTA:
+		default:
+			errs = append(errs, fmt.Errorf("%s: cpu_view debe ser raw, host o quota (actual %q)", label, c.CPUView))
+		}
+		if c.CPUThreshold < 0 || c.MemThreshold < 0 || c.MemThreshold > 100 {
+			errs = append(errs, fmt.Errorf("%s: umbrales fuera de rango (cpu %.2f, mem %.2f)", label, c.CPUThreshold, c.MemThreshold))
+		}
+		if c.MinCount < 0 {
+			errs = append(errs, fmt.Errorf("%s: min_count no puede ser negativo", label))
+		}
+
+		prevAfter := 0
+		for j, st := range c.Escalation {
+			sl := fmt.Sprintf("%s escalation[%d]", label, j)
+			switch st.Action {
+			case ACTION_THROTTLE:
+				if st.CPUs <= 0 && st.MemoryMB <= 0 {
+					errs = append(errs, fmt.Errorf("%s: throttle requiere cpus o memory_mb", sl))
+				}
+			case ACTION_PAUSE, ACTION_STOP, ACTION_REMOVE:
+			default:
+				errs = append(errs, fmt.Errorf("%s: acción desconocida %q (throttle, pause, stop, remove)", sl, st.Action))
+			}
+			if st.After <= prevAfter {
+				errs = append(errs, fmt.Errorf("%s: after debe ser mayor que el del escalón anterior (%d)", sl, prevAfter))
+			}
+			if st.Action == ACTION_REMOVE && j != len(c.Escalation)-1 {
+				errs = append(errs, fmt.Errorf("%s: remove debe ser el último escalón", sl))
+			}
+			prevAfter = st.After
+		}
+
+		cc := compiledClass{class: c}
+		for j, m := range c.Match {
+			ml := fmt.Sprintf("%s match[%d]", label, j)
+			if len(m.Images) == 0 && m.ImageRegex == "" && len(m.Names) == 0 && m.NameRegex == "" && len(m.Labels) == 0 {
+				errs = append(errs, fmt.Errorf("%s: criterio vacío", ml))
+			}
+			cm := compiledMatch{Match: m}
+			for _, g := range append(append([]string{}, m.Images...), m.Names...) {
+				if _, err := path.Match(g, ""); err != nil {
+					errs = append(errs, fmt.Errorf("%s: glob inválido %q: %v", ml, g, err))
+				}
+			}
+			var err error
+			if m.ImageRegex != "" {
+				if cm.imageRe, err = regexp.Compile("(?i)" + m.ImageRegex); err != nil {
+					errs = append(errs, fmt.Errorf("%s: image_regex inválida: %v", ml, err))
+				}
+			}
+			if m.NameRegex != "" {
+				if cm.nameRe, err = regexp.Compile("(?i)" + m.NameRegex); err != nil {
+					errs = append(errs, fmt.Errorf("%s: name_regex inválida: %v", ml, err))
+				}
+			}
+			cc.matches = append(cc.matches, cm)
+		}
+		e.classes = append(e.classes, cc)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return e, nil
+}
+
+// anyGlob indica si s coincide con alguno de los patrones (sin distinguir mayúsculas).
+func anyGlob(patterns []string, s string) bool {
+	s = globSubject(s)
+	for _, p := range patterns {
+		if ok, _ := path.Match(globSubject(p), s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// globSubject prepara un patrón o un valor para path.Match. En path.Match
+// "*" no abarca "/", pero las imágenes llevan registro y namespace
+// ("docker.io/library/high_cpu_img:latest", "localhost/app" en Podman), así
+// que "/" se reemplaza por un byte que no aparece en nombres de imágenes.
+func globSubject(s string) string {
+	return strings.ReplaceAll(strings.ToLower(s), "/", "\x00")
+}
+
+func (m *compiledMatch) matches(info var_const.DockerInfo) bool {
+	name := strings.TrimPrefix(info.Name, "/")
+
+	if len(m.Images) > 0 && !anyGlob(m.Images, info.Image) {
+		return false
+	}
+	if m.imageRe != nil && !m.imageRe.MatchString(info.Image) {
+		return false
+	}
+	if len(m.Names) > 0 && !anyGlob(m.Names, name) {
+		return false
+	}
+	if m.nameRe != nil && !m.nameRe.MatchString(name) {
+		return false
+	}
+	for k, want := range m.Labels {
+		got, ok := info.Labels[k]
+		if !ok || (want != "*" && got != want) {
+			return false
+		}
+	}
+	return true
+}
+
+// Classify retorna la primera clase que coincide con el contenedor o nil
+// si ninguna coincide (contenedor sin clasificar).
+func (e *Engine) Classify(info var_const.DockerInfo) *Class {
+	if e == nil {
+		return nil
+	}
+	for i := range e.classes {
+		cc := &e.classes[i]
+		for j := range cc.matches {
+			if cc.matches[j].matches(info) {
+				return &cc.class
+			}
+		}
+	}
+	return nil
+}
+
+// ClassByName retorna la clase con el nombre indicado o nil.
+func (e *Engine) ClassByName(name string) *Class {
+	if e == nil {
+		return nil
+	}
+	for i := range e.classes {
+		if e.classes[i].class.Name == name {
+			return &e.classes[i].class
+		}
+	}
+	return nil
+}
+
+// DefaultClasses reproduce la política histórica del daemon: grafana
+// protegido, imágenes low_img evaluadas por CPU o memoria, high_cpu_img por
+// CPU y high_mem_img por memoria, con los mínimos globales.
+func DefaultClasses(minLow, minHigh int) []Class {
+	return []Class{
+		{
+			Name:      "protected",
+			Protected: true,
+			Match: []Match{
+				{Images: []string{"*grafana*"}},
+				{Names: []string{"*grafana*"}},
+			},
+		},
+		{
+			Name:     "high-cpu",
+			Match:    []Match{{Images: []string{"*high_cpu_img*"}}},
+			Evaluate: []string{METRIC_CPU},
+			MinCount: minHigh,
+			Group:    "high",
+		},
+		{
+			Name:     "high-mem",
+			Match:    []Match{{Images: []string{"*high_mem_img*"}}},
+			Evaluate: []string{METRIC_MEM},
+			MinCount: minHigh,
+			Group:    "high",
+		},
+		{
+			Name:     "low",
+			Match:    []Match{{Images: []string{"*low_img*"}}},
+			Evaluate: []string{METRIC_CPU, METRIC_MEM},
+			MinCount: minLow,
+		},
+	}
+}
@@ -0,0 +1,60 @@
+package rules
+
+import (
+	"so1-daemon/var_const"
+	"testing"
+)
+
+func TestClassifyImagePrefixes(t *testing.T) {
+	e, err := Compile(DefaultClasses(3, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		image string
+		name  string
+		want  string // "" si no se clasifica
+	}{
+		{"high_cpu_img", "a", "high-cpu"},
+		{"high_cpu_img:latest", "a", "high-cpu"},
+		{"localhost/high_cpu_img:latest", "a", "high-cpu"},
+		{"docker.io/library/high_cpu_img:latest", "a", "high-cpu"},
+		{"registry.example.com:5000/team/high_mem_img@sha256:abcd", "a", "high-mem"},
+		{"docker.io/library/low_img:1.0", "a", "low"},
+		{"grafana/grafana:9.5.0", "web", "protected"},
+		{"docker.io/grafana/grafana-oss:latest", "web", "protected"},
+		{"GRAFANA/GRAFANA", "web", "protected"},
+		{"docker.io/library/nginx:latest", "web", ""},
+	}
+	for _, tt := range tests {
+		c := e.Classify(var_const.DockerInfo{Image: tt.image, Name: tt.name})
+		got := ""
+		if c != nil {
+			got = c.Name
+		}
+		if got != tt.want {
+			t.Errorf("Classify(%q) = %q, se esperaba %q", tt.image, got, tt.want)
+		}
+	}
+}
+
+func TestAnyGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{"*grafana*", "grafana/grafana:9.5.0", true},
+		{"grafana/*", "grafana/grafana:9.5.0", true},
+		{"docker.io/*/low_img:*", "docker.io/library/low_img:1.0", true},
+		{"low_img", "docker.io/library/low_img", false},
+		{"*/low_img", "docker.io/library/low_img", true},
+		{"high_?pu_img", "high_cpu_img", true},
+	}
+	for _, tt := range tests {
+		if got := anyGlob([]string{tt.pattern}, tt.s); got != tt.want {
+			t.Errorf("anyGlob(%q, %q) = %v, se esperaba %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
@@ -59,12 +58,6 @@ func RunCommand(name string, args ...string) (string, error) {
 	return out.String(), nil
 }
 
-var TrailingCommaRe = regexp.MustCompile(`,\s*([\]\}])`)
-
-func SanitizeJSON(b []byte) []byte {
-	return TrailingCommaRe.ReplaceAll(b, []byte("$1"))
-}
-
 func ReadProcFile(path string) ([]byte, error) {
 	f, err := os.Open(path)
 
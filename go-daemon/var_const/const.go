@@ -1,25 +1,13 @@
 package var_const
 
 import (
-	"database/sql"
 	"sync"
 	"time"
 )
 
+// Los umbrales, mínimos y rutas ajustables se encuentran en el paquete config.
 const (
-	PROC_CONT = "/proc/continfo_so1_202041390"
-	PROC_SYS  = "/proc/sysinfo_so1_202041390"
-
-	DB_PATH = "./data/monitor.db"
-
 	DOCKER_COMPOSE_F = "docker-compose.yml"
-
-	// Umbrales
-	CPU_THRESHOLD = 20.0 // %
-	MEM_THRESHOLD = 20.0 // %
-	// Mínimos
-	MIN_LOW_CONTAINERS  = 3
-	MIN_HIGH_CONTAINERS = 2
 )
 
 type ProcProcess struct {
@@ -34,37 +22,274 @@ type ProcProcess struct {
 }
 
 type ProcSys struct {
-	MemTotalKb uint64        `json:"mem_total_kb"`
-	MemFreeKb  uint64        `json:"mem_free_kb"`
-	MemUsedKb  uint64        `json:"mem_used_kb"`
-	Processes  []ProcProcess `json:"processes"`
+	SchemaVersion int           `json:"schema_version"` // 0 en módulos anteriores al esquema
+	MemTotalKb    uint64        `json:"mem_total_kb"`
+	MemFreeKb     uint64        `json:"mem_free_kb"`
+	MemUsedKb     uint64        `json:"mem_used_kb"`
+	Processes     []ProcProcess `json:"processes"`
 }
 
 type ProcCont struct {
-	MemTotalKb uint64        `json:"mem_total_kb"`
-	MemFreeKb  uint64        `json:"mem_free_kb"`
-	MemUsedKb  uint64        `json:"mem_used_kb"`
-	Containers []ProcProcess `json:"containers"`
+	SchemaVersion int           `json:"schema_version"` // 0 en módulos anteriores al esquema
+	MemTotalKb    uint64        `json:"mem_total_kb"`
+	MemFreeKb     uint64        `json:"mem_free_kb"`
+	MemUsedKb     uint64        `json:"mem_used_kb"`
+	Containers    []ProcProcess `json:"containers"`
 }
 
-// Docker container helper
+// Información de un contenedor. Se conserva el nombre DockerInfo aunque
+// también la generan los runtimes de containerd y Podman.
 type DockerInfo struct {
 	ContainerID string
 	Image       string
 	Pid         int
 	Name        string
+	Namespace   string // namespace de containerd (vacío en Docker/Podman)
+	Labels      map[string]string
+}
+
+// Unidades de los contadores de tiempo de CPU
+const (
+	CPU_UNIT_NS    = "ns"    // cgroup (cpuacct.usage, cpu.stat) y proc_jiffies del módulo
+	CPU_UNIT_TICKS = "ticks" // /proc/<pid>/stat: utime + stime en USER_HZ
+)
+
+// SampleKey identifica una serie de PrevSamples: un contenedor por su ID
+// o, si no es un contenedor, un proceso por su PID, junto con la unidad
+// del contador. Una serie nunca mezcla lecturas de fuentes distintas.
+type SampleKey struct {
+	ContainerID string
+	Pid         int // solo procesos sin contenedor
+	Unit        string
 }
 
+// CpuCounter es la última lectura de un contador acumulado de tiempo de
+// CPU y el %CPU calculado con ella.
+type CpuCounter struct {
+	Value uint64 // último contador leído, en la unidad de la serie
+	// Inicio del proceso (starttime de /proc/<pid>/stat); si cambia el PID
+	// fue reutilizado por otro proceso. 0 si la fuente no lo publica.
+	StartTime uint64
+	Timestamp time.Time
+
+	// Resultado calculado en el ciclo Timestamp. Un contenedor con varios
+	// procesos consulta su serie más de una vez por ciclo.
+	LastPct float64
+	LastOk  bool
+}
+
+// PidCpuSample es el estado de una serie de PrevSamples: la última lectura
+// del contador de CPU y el historial de uso para la detección sostenida.
 type PidCpuSample struct {
-	TotalProcessTime   uint64 // ticks
-	TotalSystemJiffies uint64
-	Timestamp          time.Time
+	CpuCounter
+
+	// Ciclo de la última muestra agregada al historial
+	RecordedAt time.Time
+	// Historial de uso de los últimos ciclos (ventana de detección)
+	History []UsageSample
+	// Promedio móvil exponencial de CPU y memoria. EwmaCpuOk es false
+	// mientras no haya ningún %CPU válido
+	EwmaCpu   CpuViews
+	EwmaCpuOk bool
+	EwmaMem   float64
 }
 
-var (
-	DB     *sql.DB
-	DBLock sync.Mutex
+// UsageSample es el uso medido de un proceso en un ciclo. Sin un %CPU
+// válido (CpuOk false) Cpu queda en 0 y solo cuenta la memoria.
+type UsageSample struct {
+	Cpu   CpuViews
+	CpuOk bool
+	Mem   float64
+}
+
+// CpuViews es el %CPU de un contenedor en las escalas contra las que una
+// clase puede expresar su umbral (ver rules.CPU_VIEW_*).
+type CpuViews struct {
+	Raw   float64 `json:"raw"`   // estilo docker stats: 100% por núcleo
+	Host  float64 `json:"host"`  // respecto de todos los núcleos del host
+	Quota float64 `json:"quota"` // respecto de la cuota de CPU del contenedor
+}
 
-	PrevSamples     = make(map[int]PidCpuSample)
+// CgroupStats son los recursos del cgroup de un contenedor leídos por
+// ReadCgroupStats. La memoria está en bytes; MemoryMax y CpuLimit en 0
+// significan sin límite. Las presiones (PSI) solo existen en cgroups v2.
+type CgroupStats struct {
+	Version       int    `json:"version"` // 1 o 2
+	MemoryCurrent uint64 `json:"memory_current"`
+	// Uso sin la caché inactiva (inactive_file), igual que docker stats
+	MemoryWorkingSet uint64   `json:"memory_working_set"`
+	MemoryMax        uint64   `json:"memory_max"`
+	MemoryAnon       uint64   `json:"memory_anon"`
+	MemoryFile       uint64   `json:"memory_file"`
+	Io               CgroupIo `json:"io"`
+	PidsCurrent      uint64   `json:"pids_current"`
+	// CPUs permitidas por la cuota (cpu.max o cfs_quota_us/cfs_period_us);
+	// 0 significa sin cuota
+	CpuLimit       float64   `json:"cpu_limit"`
+	CpuPressure    *Pressure `json:"cpu_pressure,omitempty"`
+	MemoryPressure *Pressure `json:"memory_pressure,omitempty"`
+	IoPressure     *Pressure `json:"io_pressure,omitempty"`
+}
+
+// CgroupIo son los contadores acumulados de E/S de bloque del cgroup,
+// sumados en todos los dispositivos.
+type CgroupIo struct {
+	ReadBytes  uint64 `json:"read_bytes"`
+	WriteBytes uint64 `json:"write_bytes"`
+	ReadOps    uint64 `json:"read_ops"`
+	WriteOps   uint64 `json:"write_ops"`
+}
+
+// Pressure es el contenido de un archivo PSI (cpu/memory/io.pressure).
+type Pressure struct {
+	Some PressureLine `json:"some"`
+	Full PressureLine `json:"full"`
+}
+
+// PressureLine son los promedios (%) de tiempo con tareas bloqueadas por
+// el recurso y el total acumulado en microsegundos.
+type PressureLine struct {
+	Avg10     float64 `json:"avg10"`
+	Avg60     float64 `json:"avg60"`
+	Avg300    float64 `json:"avg300"`
+	TotalUsec uint64  `json:"total_usec"`
+}
+
+// SystemStatus son las métricas del sistema leídas en el último ciclo.
+type SystemStatus struct {
+	MemTotalKb uint64        `json:"mem_total_kb"`
+	MemFreeKb  uint64        `json:"mem_free_kb"`
+	MemUsedKb  uint64        `json:"mem_used_kb"`
+	Processes  int           `json:"processes"`
+	States     ProcessStates `json:"states"`
+	Cpu        *CpuUsage     `json:"cpu,omitempty"`
+	Cores      []CoreUsage   `json:"cores"`
+}
+
+// ProcessStates cuenta los procesos según el carácter de estado que
+// publica el módulo del kernel (task_state_to_char).
+type ProcessStates struct {
+	Running   int `json:"running"`    // R
+	Sleeping  int `json:"sleeping"`   // S
+	DiskSleep int `json:"disk_sleep"` // D, espera ininterrumpible
+	Stopped   int `json:"stopped"`    // T y t (detenido o en traza)
+	Zombie    int `json:"zombie"`     // Z
+	Idle      int `json:"idle"`       // I, hilos del kernel inactivos
+	Other     int `json:"other"`      // X, P o estado desconocido
+}
+
+// Snapshot agrupa el resultado del último ciclo de monitoreo.
+type Snapshot struct {
+	Timestamp  time.Time         `json:"ts"`
+	System     SystemStatus      `json:"system"`
+	Containers []ContainerSample `json:"containers"`
+}
+
+// SysSample es una fila de la tabla sys_metrics.
+// Cpu es nil cuando aún no hay una lectura previa de /proc/stat.
+type SysSample struct {
+	MemTotalKb uint64    `json:"mem_total_kb"`
+	MemFreeKb  uint64    `json:"mem_free_kb"`
+	MemUsedKb  uint64    `json:"mem_used_kb"`
+	Cpu        *CpuUsage `json:"cpu,omitempty"`
+	Ts         int64     `json:"ts"`
+}
+
+// CpuUsage es el reparto (%) del tiempo de CPU entre dos lecturas de
+// /proc/stat. User incluye nice y System incluye irq y softirq; Busy es
+// todo lo que no es idle ni iowait.
+type CpuUsage struct {
+	BusyPct   float64 `json:"busy_pct"`
+	UserPct   float64 `json:"user_pct"`
+	SystemPct float64 `json:"system_pct"`
+	IdlePct   float64 `json:"idle_pct"`
+	IowaitPct float64 `json:"iowait_pct"`
+	StealPct  float64 `json:"steal_pct"`
+}
+
+// CoreUsage es el uso de un núcleo (línea cpuN de /proc/stat).
+type CoreUsage struct {
+	Cpu int `json:"cpu"`
+	CpuUsage
+}
+
+// CoreSample es una fila de la tabla cpu_cores.
+type CoreSample struct {
+	CoreUsage
+	Ts int64 `json:"ts"`
+}
+
+// ProcessCountSample es una fila de la tabla process_count.
+type ProcessCountSample struct {
+	Total int `json:"total"`
+	ProcessStates
+	Ts int64 `json:"ts"`
+}
+
+// ProcessSample es una fila de la tabla processes: un proceso del host
+// medido en un ciclo.
+type ProcessSample struct {
+	Pid     int     `json:"pid"`
+	Name    string  `json:"name"`
+	Cmdline string  `json:"cmdline"`
+	RssKb   uint64  `json:"rss_kb"`
+	VszKb   uint64  `json:"vsz_kb"`
+	MemPct  float64 `json:"mem_pct"`
+	State   string  `json:"state"`
+	CpuPct  float64 `json:"cpu_pct"`
+	Ts      int64   `json:"ts"`
+}
+
+// ContainerSample es una fila de la tabla containers y el estado de un
+// contenedor en Snapshot (la misma muestra del ciclo). Decision y Reason
+// explican qué hizo el daemon con el contenedor en ese ciclo. RssKb y
+// VszKb suman todas sus tareas (Tasks); si no se pudieron listar son las
+// del proceso publicado por el módulo del kernel.
+type ContainerSample struct {
+	ContainerID string  `json:"container_id"`
+	Pid         int     `json:"pid"`
+	Name        string  `json:"name"`
+	Image       string  `json:"image"`
+	Class       string  `json:"class"`
+	State       string  `json:"state"`
+	RssKb       uint64  `json:"rss_kb"`
+	VszKb       uint64  `json:"vsz_kb"`
+	Tasks       int     `json:"tasks"`
+	CpuPct      float64 `json:"cpu_pct"`
+	CpuHostPct  float64 `json:"cpu_host_pct"`
+	CpuQuotaPct float64 `json:"cpu_quota_pct"`
+	MemPct      float64 `json:"mem_pct"`
+	Decision    string  `json:"decision"`
+	Reason      string  `json:"reason"`
+	// Recursos del cgroup; nil si no se pudo leer o no es un contenedor.
+	// Al leer de la base solo se recuperan los promedios avg10 de PSI.
+	Cgroup *CgroupStats `json:"cgroup,omitempty"`
+	Ts     int64        `json:"ts"`
+}
+
+// DeletionRecord es una fila de la tabla deletions.
+type DeletionRecord struct {
+	ContainerID string `json:"container_id"`
+	Reason      string `json:"reason"`
+	Ts          int64  `json:"ts"`
+}
+
+// ActionRecord es una fila de la tabla actions.
+type ActionRecord struct {
+	ContainerID string  `json:"container_id"`
+	Image       string  `json:"image"`
+	Class       string  `json:"class"`
+	Action      string  `json:"action"`
+	Reason      string  `json:"reason"`
+	CpuPct      float64 `json:"cpu_pct"`
+	MemPct      float64 `json:"mem_pct"`
+	DryRun      bool    `json:"dry_run"`
+	Ts          int64   `json:"ts"`
+}
+
+// Series de uso de CPU por contenedor o proceso. Las entradas de los que
+// ya no existen se descartan en cada ciclo.
+var (
+	PrevSamples     = make(map[SampleKey]PidCpuSample)
 	PrevSamplesLock sync.Mutex
 )
@@ -24,6 +24,11 @@
 
 #define CMDLINE_MAX 512
 
+/* Versión del formato JSON de los archivos /proc (campo "schema_version").
+ * Agregar campos no la cambia; quitar un campo o cambiar su tipo sí, y el
+ * daemon rechaza las versiones que no conoce. */
+#define PROC_SCHEMA_VERSION 1
+
 /* helper: obtener memoria total/free en KB */
 static void get_meminfo_kb(unsigned long *total_kb, unsigned long *free_kb)
 {
@@ -93,6 +98,48 @@ static int read_task_cmdline(struct task_struct *task, char *buf, int bufsize)
     return ret;
 }
 
+/* helper: escribir s como cadena JSON, escapando comillas, barras
+ * invertidas y caracteres de control (cmdline y comm los controla el
+ * proceso y pueden contener cualquier byte) */
+static void seq_json_string(struct seq_file *m, const char *s)
+{
+    seq_putc(m, '"');
+    for (; *s; ++s) {
+        unsigned char c = (unsigned char)*s;
+        switch (c) {
+        case '"':  seq_puts(m, "\\\""); break;
+        case '\\': seq_puts(m, "\\\\"); break;
+        case '\n': seq_puts(m, "\\n"); break;
+        case '\r': seq_puts(m, "\\r"); break;
+        case '\t': seq_puts(m, "\\t"); break;
+        default:
+            if (c < 0x20 || c == 0x7f)
+                seq_printf(m, "\\u%04x", c);
+            else
+                seq_putc(m, c);
+        }
+    }
+    seq_putc(m, '"');
+}
+
+/* helper: escribir un proceso como objeto JSON en una sola línea. El
+ * separador lo escribe quien llama (sin coma después del último). */
+static void seq_json_process(struct seq_file *m, struct task_struct *task,
+                             const char *cmdline, unsigned long vsz_kb,
+                             unsigned long rss_kb, unsigned long pct_x100,
+                             unsigned long long proc_jiffies, char state)
+{
+    seq_printf(m, "    { \"pid\": %d, \"name\": ", task->pid);
+    seq_json_string(m, task->comm);
+    seq_puts(m, ", \"cmdline\": ");
+    seq_json_string(m, cmdline);
+    seq_printf(m,
+               ", \"vsz_kb\": %lu, \"rss_kb\": %lu, "
+               "\"mem_pct\": \"%lu.%02lu\", \"proc_jiffies\": %llu, \"state\": \"%c\" }",
+               vsz_kb, rss_kb, pct_x100 / 100, pct_x100 % 100,
+               proc_jiffies, state);
+}
+
 /* helper simple para calcular porcentaje (con cuidado en enteros) */
 static unsigned long percent_of_x100(unsigned long part_kb, unsigned long total_kb)
 {
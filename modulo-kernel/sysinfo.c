@@ -8,11 +8,13 @@ static int sys_show(struct seq_file *m, void *v)
     unsigned long total_kb, free_kb, used_kb;
     struct task_struct *task;
     unsigned long long proc_jiffies;
+    bool first = true;
 
     get_meminfo_kb(&total_kb, &free_kb);
     used_kb = total_kb - free_kb;
 
     seq_printf(m, "{\n");
+    seq_printf(m, "  \"schema_version\": %d,\n", PROC_SCHEMA_VERSION);
     seq_printf(m, "  \"mem_total_kb\": %lu,\n", total_kb);
     seq_printf(m, "  \"mem_free_kb\": %lu,\n", free_kb);
     seq_printf(m, "  \"mem_used_kb\": %lu,\n", used_kb);
@@ -30,24 +32,19 @@ static int sys_show(struct seq_file *m, void *v)
         get_mem_from_mm(task->mm, &vsz_kb, &rss_kb);
         read_task_cmdline(task, cmdline, CMDLINE_MAX);
 
-        /* calcular % MEM sin double */
-        unsigned long pct_x100 = percent_of_x100(vsz_kb, total_kb);
-        unsigned long pct_int = pct_x100 / 100;
-        unsigned long pct_dec = pct_x100 % 100;
-
-        seq_printf(m,
-                   "    { \"pid\": %d, \"name\": \"%s\", \"cmdline\": \"%s\", "
-                   "\"vsz_kb\": %lu, \"rss_kb\": %lu, "
-                   "\"mem_pct\": \"%lu.%02lu\", \"proc_jiffies\": %llu, \"state\": \"%c\" },\n",
-                   task->pid, task->comm, cmdline,
-                   vsz_kb, rss_kb, pct_int, pct_dec,
-                   proc_jiffies, state);
+        /* coma antes de cada registro salvo el primero: sin coma final
+         * el JSON es válido (el % MEM se calcula sin float) */
+        if (!first)
+            seq_printf(m, ",\n");
+        first = false;
+        seq_json_process(m, task, cmdline, vsz_kb, rss_kb,
+                         percent_of_x100(vsz_kb, total_kb), proc_jiffies, state);
 
        
     }
     rcu_read_unlock();
 
-    seq_printf(m, "  ]\n}\n");
+    seq_printf(m, "\n  ]\n}\n");
     return 0;
 }
 